@@ -0,0 +1,51 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// ParseAlias returns the command line of alias text. An alias may only be
+// a simple command with leading arguments.
+func ParseAlias(text string) (*shellutils.Cmdline, error) {
+	script := lines{text}
+	ls, err := shellutils.Parse("", &script)
+	if err != nil {
+		return nil, err
+	}
+	if len(ls.Cmds) != 1 || len(ls.Cmds[0].Term.String()) > 0 {
+		return nil, fmt.Errorf("%q: not a simple command", text)
+	}
+	return &ls.Cmds[0], nil
+}
+
+// expandAlias replaces the command name with its alias. The first word of
+// the alias is itself expanded unless it's an alias already replaced.
+func (g *Goes) expandAlias(args []string) []string {
+	if len(g.AliasMap) == 0 {
+		return args
+	}
+	expanded := make(map[string]bool)
+	for len(args) > 0 {
+		text, found := g.AliasMap[args[0]]
+		if !found || expanded[args[0]] {
+			break
+		}
+		expanded[args[0]] = true
+		cl, err := ParseAlias(text)
+		if err != nil {
+			break
+		}
+		_, words := cl.SliceSubst(g.Getenv, g.Cmdsubst)
+		if len(words) == 0 {
+			break
+		}
+		args = append(words, args[1:]...)
+	}
+	return args
+}
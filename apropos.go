@@ -33,16 +33,17 @@ func (g *Goes) apropos(args ...string) error {
 		}
 	}
 	if len(args) == 0 {
+		g.loadPlugins()
 		args = g.Names()
 	}
 	for i, name := range args {
 		if len(name) == 0 {
 			continue
 		}
-		if cmd, found := g.ByName[name]; found {
+		if cmd, found := g.lookup(name); found {
 			fmt.Print(name)
 			pad(16 - len(name))
-			fmt.Println(cmd.Apropos())
+			fmt.Println(g.aproposOf(name, cmd))
 		} else if i == 0 {
 			return fmt.Errorf("%s: not found", name)
 		}
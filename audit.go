@@ -0,0 +1,265 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/platinasystems/goes/external/log"
+)
+
+// The commands of a session are audited once it has an id in the
+// environment of its processes. Telnetd and sshd also name the remote
+// address and user of their sessions.
+const (
+	AuditSessionEnv = "GOES_SESSION"
+	AuditUserEnv    = "GOES_USER"
+	AuditRemoteEnv  = "GOES_REMOTE"
+)
+
+// auditSession, auditUserName and auditRemote are those of the process when
+// started, so that they can't be changed by the session with export or env.
+var (
+	auditSession  = os.Getenv(AuditSessionEnv)
+	auditUserName = os.Getenv(AuditUserEnv)
+	auditRemote   = os.Getenv(AuditRemoteEnv)
+)
+
+// AuditFile has a JSON AuditRecord appended for each command, in addition
+// to those logged with the audit facility. It's not written if empty.
+var AuditFile = "/etc/goes/audit"
+
+// AuditHistory limits the records of this process kept for AuditRecords.
+var AuditHistory = 100
+
+// An AuditRecord is that of a command run by a session.
+type AuditRecord struct {
+	Time     time.Time     `json:"time"`
+	Session  string        `json:"session"`
+	User     string        `json:"user,omitempty"`
+	Remote   string        `json:"remote,omitempty"`
+	TTY      string        `json:"tty,omitempty"`
+	Args     []string      `json:"args"`
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"duration"`
+}
+
+var audit struct {
+	sync.Mutex
+	tty     string
+	records []AuditRecord
+	// env are the variables of the session as last seen in the
+	// environment of this process, see auditEnvChanged
+	env map[string]string
+	// fileErr is the last error appending AuditFile, which is logged
+	// just once rather than with each command.
+	fileErr string
+}
+
+// EnableAudit starts a session, if not already within one, so that the
+// commands run by this and its child processes are audited.
+func EnableAudit() {
+	if len(auditSession) == 0 {
+		auditSession = NewAuditSession()
+		os.Setenv(AuditSessionEnv, auditSession)
+	}
+}
+
+// NewAuditSession returns a unique session id.
+func NewAuditSession() string {
+	id := strconv.FormatInt(time.Now().UnixNano(), 36)
+	return fmt.Sprint(id, "-", os.Getpid())
+}
+
+// IsAudited is true within a session.
+func IsAudited() bool {
+	return len(auditSession) > 0
+}
+
+// Audit logs the command run by the current session with its exit status.
+func Audit(args []string, start time.Time, err error) {
+	if !IsAudited() {
+		return
+	}
+	r := AuditRecord{
+		Time:     start,
+		Session:  auditSession,
+		User:     auditUser(),
+		Remote:   auditRemote,
+		TTY:      auditTTY(),
+		Args:     args,
+		Status:   ExitStatus(err),
+		Duration: time.Since(start),
+	}
+	r.Log()
+	auditEnvChanged(r)
+}
+
+// auditEnvChanged logs the change of a variable of the session in the
+// environment of this process, e.g. with export, by the command of the
+// record. Such changes don't change the records of the session.
+func auditEnvChanged(r AuditRecord) {
+	audit.Lock()
+	defer audit.Unlock()
+	if audit.env == nil {
+		audit.env = map[string]string{
+			AuditSessionEnv: auditSession,
+			AuditUserEnv:    auditUserName,
+			AuditRemoteEnv:  auditRemote,
+		}
+	}
+	for _, k := range []string{
+		AuditSessionEnv,
+		AuditUserEnv,
+		AuditRemoteEnv,
+	} {
+		v := os.Getenv(k)
+		if v == audit.env[k] {
+			continue
+		}
+		audit.env[k] = v
+		if len(v) == 0 {
+			log.Print("audit", "err", r, ": ", k, ": unset")
+		} else {
+			log.Print("audit", "err", r, ": ", k, ": changed to ",
+				strconv.Quote(v))
+		}
+	}
+}
+
+// auditEnv adds the variables of the session of this process to the
+// environment of a child, replacing any others, so that its commands are
+// audited as those of the session.
+func auditEnv(env []string) []string {
+	if env == nil {
+		env = os.Environ()
+	}
+	kept := env[:0:0]
+	for _, s := range env {
+		if !strings.HasPrefix(s, AuditSessionEnv+"=") &&
+			!strings.HasPrefix(s, AuditUserEnv+"=") &&
+			!strings.HasPrefix(s, AuditRemoteEnv+"=") {
+			kept = append(kept, s)
+		}
+	}
+	for _, kv := range [][2]string{
+		{AuditSessionEnv, auditSession},
+		{AuditUserEnv, auditUserName},
+		{AuditRemoteEnv, auditRemote},
+	} {
+		if len(kv[1]) > 0 {
+			kept = append(kept, kv[0]+"="+kv[1])
+		}
+	}
+	return kept
+}
+
+// Log the record with the audit facility, to AuditFile, and in the history
+// of AuditRecords.
+func (r AuditRecord) Log() {
+	log.Print("audit", "info", r)
+	audit.Lock()
+	defer audit.Unlock()
+	if AuditHistory > 0 {
+		if len(audit.records) >= AuditHistory {
+			audit.records = audit.records[1:]
+		}
+		audit.records = append(audit.records, r)
+	}
+	if len(AuditFile) > 0 {
+		if err := r.append(AuditFile); err == nil {
+			audit.fileErr = ""
+		} else if s := err.Error(); s != audit.fileErr {
+			log.Print("audit", "err", AuditFile, ": ", err)
+			audit.fileErr = s
+		}
+	}
+}
+
+// AuditRecords returns the latest records of the commands run by this
+// process.
+func AuditRecords() []AuditRecord {
+	audit.Lock()
+	defer audit.Unlock()
+	return append([]AuditRecord{}, audit.records...)
+}
+
+// String formats the record as logged, e.g.
+//
+//	session=kf3...-42 user=root remote=10.0.0.1:53312 tty=/dev/pts/0
+//	status=0 duration=1.2ms: ip link show
+func (r AuditRecord) String() string {
+	var fields []string
+	for _, kv := range [][2]string{
+		{"session", r.Session},
+		{"user", r.User},
+		{"remote", r.Remote},
+		{"tty", r.TTY},
+	} {
+		if len(kv[1]) > 0 {
+			fields = append(fields, kv[0]+"="+kv[1])
+		}
+	}
+	fields = append(fields, fmt.Sprint("status=", r.Status),
+		fmt.Sprint("duration=", r.Duration.Round(time.Microsecond)))
+	args := make([]string, len(r.Args))
+	for i, arg := range r.Args {
+		args[i] = arg
+		if len(arg) == 0 || strings.ContainsAny(arg, " \t\n\"'\\") {
+			args[i] = strconv.Quote(arg)
+		}
+	}
+	return strings.Join(fields, " ") + ": " + strings.Join(args, " ")
+}
+
+func (r AuditRecord) append(fn string) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fn, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	// a single write of each line keeps those of other sessions whole
+	_, err = f.Write(append(b, '\n'))
+	if t := f.Close(); err == nil {
+		err = t
+	}
+	return err
+}
+
+func auditUser() string {
+	if len(auditUserName) > 0 {
+		return auditUserName
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(os.Getuid())
+}
+
+func auditTTY() string {
+	audit.Lock()
+	defer audit.Unlock()
+	if len(audit.tty) == 0 {
+		s, err := os.Readlink("/proc/self/fd/0")
+		if err != nil || !strings.HasPrefix(s, "/dev/") {
+			s = "-"
+		}
+		audit.tty = s
+	}
+	if audit.tty == "-" {
+		return ""
+	}
+	return audit.tty
+}
@@ -15,47 +15,54 @@ type cache struct {
 	builtins map[string]func(...string) error
 	names    []string
 	path     []string
+	plugins  map[string]*plugin
 }
 
 func (g *Goes) Builtins() map[string]func(...string) error {
-	if g.cache.builtins == nil || len(g.cache.builtins) == 0 {
-		g.cache.Lock()
-		defer g.cache.Unlock()
+	g.cache.Lock()
+	defer g.cache.Unlock()
+	if len(g.cache.builtins) == 0 {
 		g.cache.builtins = map[string]func(...string) error{
 			"apropos":  g.apropos,
 			"complete": g.complete,
 			"help":     g.help,
 			"man":      g.man,
+			"rehash":   g.rehash,
 			"usage":    g.usage,
 		}
 	}
 	return g.cache.builtins
 }
 
+// Names returns the sorted names of the commands, including the plugins of
+// the top goes. The result is shared, so mustn't be modified.
 func (g *Goes) Names() []string {
-	if len(g.cache.names) < len(g.ByName) {
-		g.cache.Lock()
-		defer g.cache.Unlock()
-		if got, want := len(g.cache.names), len(g.ByName); got < want {
-			if got == 0 {
-				g.cache.names = make([]string, 0, want)
-			} else {
-				g.cache.names = g.cache.names[:0]
-			}
-			for k := range g.ByName {
-				g.cache.names = append(g.cache.names, k)
-			}
-			sort.Strings(g.cache.names)
+	if g.shell != nil {
+		return g.shell.Names()
+	}
+	g.cache.Lock()
+	defer g.cache.Unlock()
+	want := len(g.ByName) + len(g.cache.plugins)
+	if len(g.cache.names) < want {
+		// anew, rather than over those returned before
+		names := make([]string, 0, want)
+		for k := range g.ByName {
+			names = append(names, k)
+		}
+		for k := range g.cache.plugins {
+			names = append(names, k)
 		}
+		sort.Strings(names)
+		g.cache.names = names
 	}
 	return g.cache.names
 }
 
 // set Path of sub-goes. e.g. "ip address"
 func (g *Goes) Path() []string {
+	g.cache.Lock()
+	defer g.cache.Unlock()
 	if g.parent != nil && len(g.cache.path) == 0 {
-		g.cache.Lock()
-		defer g.cache.Unlock()
 		for p := g; p != nil; p = p.parent {
 			g.cache.path = append([]string{p.String()},
 				g.cache.path...)
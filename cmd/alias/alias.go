@@ -0,0 +1,90 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package alias
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "alias" }
+
+func (*Command) Usage() string { return "alias [NAME[=VALUE]]..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "define or print command aliases",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Define NAME as an alias of the VALUE command text. Thereafter, a
+	command named NAME is run as VALUE followed by any other arguments.
+	VALUE may only be a simple command with leading arguments, not a
+	pipeline or list.
+
+	With just NAME, print its alias; without arguments, print all
+	aliases.
+
+EXAMPLES
+	alias ll='ls -l'
+	alias rt='ip route show table'
+	rt main`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	if len(args) == 0 {
+		names := make([]string, 0, len(c.g.AliasMap))
+		for name := range c.g.AliasMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		args = names
+	}
+	for _, arg := range args {
+		eq := strings.Index(arg, "=")
+		if eq < 0 {
+			value, found := c.g.AliasMap[arg]
+			if !found {
+				return fmt.Errorf("%s: not found", arg)
+			}
+			fmt.Printf("alias %s=%s\n", arg, quote(value))
+			continue
+		}
+		name, value := arg[:eq], arg[eq+1:]
+		if len(name) == 0 || strings.ContainsAny(name, " \t/$'\"") {
+			return fmt.Errorf("%s: invalid alias name", name)
+		}
+		if _, err := goes.ParseAlias(value); err != nil {
+			return err
+		}
+		if c.g.AliasMap == nil {
+			c.g.AliasMap = make(map[string]string)
+		}
+		c.g.AliasMap[name] = value
+	}
+	return nil
+}
+
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,416 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package apid is a daemon that runs goes commands for HTTP clients.
+package apid
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/external/log"
+	"github.com/platinasystems/goes/external/options"
+	"github.com/platinasystems/goes/internal/prog"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+// WaitDelay limits the wait for the output of a command once its cli has
+// exited or been killed, as a child that's left the process group of the
+// cli may hold its pipes.
+var WaitDelay = time.Second
+
+var opts = options.Options{
+	{Name: "-socket", Type: options.File, Arg: "FILE",
+		Default: "/run/goes/socks/apid",
+		Help:    "Serve the API on this unix socket."},
+	{Name: "-addr", Type: options.String, Arg: "ADDRESS",
+		Help: "Also serve the API on this TCP address, e.g. :8022"},
+	{Name: "-tokens", Type: options.File, Arg: "FILE",
+		Default: "/etc/goes/apid/tokens",
+		Help:    "Authenticate clients with the tokens of this file."},
+}
+
+type Command struct{}
+
+func (Command) String() string { return "goes-apid" }
+
+func (Command) Usage() string { return opts.Usage("goes-apid") }
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "HTTP command API daemon",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Run goes commands for HTTP clients. The command path and any other
+	arguments are given by the URL; stdin by the request body, e.g.
+
+	curl -N --unix-socket /run/goes/socks/apid \
+		-X POST --data-binary @- \
+		'http://localhost/run/ip/link/show?arg=eth0' </dev/null
+
+	The response is a stream of JSON lines with the output of the command
+	as it's written, ending with its exit status.
+
+	{"stdout":"2: eth0: <BROADCAST,MULTICAST,UP,LOWER_UP> ...\n"}
+	{"stderr":"..."}
+	{"status":0}
+
+	A multibyte character split between writes is sent whole with the
+	next. The command and its children are killed if the client goes
+	away before it's done.
+
+	Each request is run by a cli, so the command may be a function,
+	builtin or any other command of the cli, which is audited as that of
+	a session with the user and remote address of the client.
+
+AUTHENTICATION
+	TCP clients must give a token with the header,
+
+	Authorization: Bearer TOKEN
+
+	that is listed, with its user, in the tokens file like this,
+
+	# TOKEN USER
+	4f0c9a71d2b3e8f6 alice
+
+	Unix socket clients without a token are those of their process user.
+	With /etc/goes/sshd/roles, the commands of each user are limited to
+	those of its role as with sshd.
+
+` + opts.Man(),
+	}
+}
+
+func (Command) Kind() cmd.Kind { return cmd.Daemon }
+
+func (Command) Options() options.Options { return opts }
+
+func (Command) Main(args ...string) error {
+	opt, args, err := opts.Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	srv := &http.Server{
+		Handler: &server{tokens: opt.String("-tokens")},
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, connKey{}, c)
+		},
+	}
+	fn := opt.String("-socket")
+	if err = os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		return err
+	}
+	os.Remove(fn)
+	ln, err := net.Listen("unix", fn)
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(fn, 0660); err != nil {
+		ln.Close()
+		return err
+	}
+	listeners := []net.Listener{ln}
+	if addr := opt.String("-addr"); len(addr) > 0 {
+		ln, err = net.Listen("tcp", addr)
+		if err != nil {
+			listeners[0].Close()
+			return err
+		}
+		listeners = append(listeners, ln)
+	}
+	for _, ln := range listeners {
+		goes.WG.Add(1)
+		go func(ln net.Listener) {
+			defer goes.WG.Done()
+			err := srv.Serve(ln)
+			if err != http.ErrServerClosed {
+				log.Print("goes-apid ", ln.Addr(), ": ", err)
+			}
+		}(ln)
+	}
+	<-goes.Stop
+	return srv.Close()
+}
+
+type connKey struct{}
+
+type server struct {
+	tokens string
+}
+
+// A frame is a line of the response stream.
+type frame struct {
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	Status *int   `json:"status,omitempty"`
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, r.Method+": unsupported",
+			http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/run/") {
+		http.NotFound(w, r)
+		return
+	}
+	var args []string
+	for _, word := range strings.Split(r.URL.Path[len("/run/"):], "/") {
+		if len(word) > 0 {
+			args = append(args, word)
+		}
+	}
+	args = append(args, r.URL.Query()["arg"]...)
+	if len(args) == 0 {
+		http.Error(w, "COMMAND: missing", http.StatusBadRequest)
+		return
+	}
+	name, err := s.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	role, err := roleOf(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	s.run(w, r, name, role, args)
+}
+
+// run the command with a cli that's given the session of the request and
+// stream its output as JSON lines.
+func (s *server) run(w http.ResponseWriter, r *http.Request, name, role string,
+	args []string) {
+	words := make([]string, len(args))
+	for i, arg := range args {
+		word := shellutils.Word{Tokens: []shellutils.Token{
+			{V: arg, T: shellutils.TokenLiteral},
+		}}
+		words[i] = word.Quote()
+	}
+	x := prog.Command("cli", "-c", strings.Join(words, " "))
+	x.Env = append(os.Environ(),
+		goes.AuditSessionEnv+"="+goes.NewAuditSession(),
+		goes.AuditUserEnv+"="+name,
+		goes.AuditRemoteEnv+"="+r.RemoteAddr)
+	if len(role) > 0 {
+		x.Env = append(x.Env, goes.AuthRoleEnv+"="+role)
+	}
+	out := &stream{w: w}
+	out.f, _ = w.(http.Flusher)
+	x.Stdin = r.Body
+	x.Stdout = out.writer(func(b []byte) frame {
+		return frame{Stdout: string(b)}
+	})
+	x.Stderr = out.writer(func(b []byte) frame {
+		return frame{Stderr: string(b)}
+	})
+	// the cli and its children are a process group that's killed once
+	// the client is gone, and those that escape it don't hold the wait
+	x.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	x.WaitDelay = WaitDelay
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if err := x.Start(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-r.Context().Done():
+			syscall.Kill(-x.Process.Pid, syscall.SIGKILL)
+		case <-done:
+		}
+	}()
+	err := x.Wait()
+	close(done)
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// the cli succeeded but left a child with its output
+		err = nil
+	}
+	x.Stdout.(*streamWriter).flush()
+	x.Stderr.(*streamWriter).flush()
+	status := goes.ExitStatus(err)
+	out.send(frame{Status: &status})
+}
+
+// authenticate returns the user of the request's token or, without one,
+// that of a unix socket client.
+func (s *server) authenticate(r *http.Request) (string, error) {
+	if auth := r.Header.Get("Authorization"); len(auth) > 0 {
+		token := strings.TrimPrefix(auth, "Bearer ")
+		if token == auth {
+			return "", fmt.Errorf("%s: unsupported",
+				strings.Fields(auth)[0])
+		}
+		return s.userOf(token)
+	}
+	c, _ := r.Context().Value(connKey{}).(net.Conn)
+	if uc, ok := c.(*net.UnixConn); ok {
+		return peerUser(uc)
+	}
+	return "", fmt.Errorf("token: missing")
+}
+
+// userOf returns the user of the token in the tokens file, which is read
+// with each request so that changes apply without restart.
+func (s *server) userOf(token string) (string, error) {
+	f, err := os.Open(s.tokens)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(fields[0]),
+			[]byte(token)) == 1 {
+			return fields[1], nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("token: invalid")
+}
+
+func peerUser(uc *net.UnixConn) (string, error) {
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+	var cred *syscall.Ucred
+	cerr := raw.Control(func(fd uintptr) {
+		cred, err = syscall.GetsockoptUcred(int(fd),
+			syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if cerr != nil {
+		return "", cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	uid := strconv.Itoa(int(cred.Uid))
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username, nil
+	}
+	return uid, nil
+}
+
+// roleOf returns the role of the user in goes.RolesFile, if any. Without
+// such a file, all have full access.
+func roleOf(name string) (string, error) {
+	roles, err := goes.LoadRoles(goes.RolesFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	role, found := roles.Of(name, "")
+	if !found {
+		return "", fmt.Errorf("%s: no role", name)
+	}
+	return role, nil
+}
+
+// A stream writes the frames of the stdout and stderr of a command as they
+// are written.
+type stream struct {
+	sync.Mutex
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+// A streamWriter keeps a trailing incomplete UTF-8 sequence for the next
+// write, as each frame is a JSON string.
+type streamWriter struct {
+	s       *stream
+	wrap    func([]byte) frame
+	partial []byte
+}
+
+func (s *stream) writer(wrap func([]byte) frame) *streamWriter {
+	return &streamWriter{s: s, wrap: wrap}
+}
+
+func (s *stream) send(fr frame) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fr); err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if _, err := s.w.Write(b.Bytes()); err != nil {
+		return err
+	}
+	if s.f != nil {
+		s.f.Flush()
+	}
+	return nil
+}
+
+func (w *streamWriter) Write(b []byte) (int, error) {
+	buf := append(w.partial, b...)
+	n := len(buf)
+	for i := n - 1; i >= 0 && i >= n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				n = i
+			}
+			break
+		}
+	}
+	w.partial = append([]byte(nil), buf[n:]...)
+	if n > 0 {
+		if err := w.s.send(w.wrap(buf[:n])); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// flush sends what's left of an incomplete sequence once the command is
+// done.
+func (w *streamWriter) flush() {
+	if len(w.partial) > 0 {
+		w.s.send(w.wrap(w.partial))
+		w.partial = nil
+	}
+}
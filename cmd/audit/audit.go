@@ -0,0 +1,126 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/external/flags"
+	"github.com/platinasystems/goes/external/parms"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "audit" }
+
+func (Command) Usage() string { return "audit [-a] [-n COUNT]" }
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "print the audit records of commands",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Print the audit record of each command run by this cli, like this,
+
+	2021-06-01T12:00:00Z session=kf3x9-42 user=admin remote=10.0.0.1:53312
+	tty=/dev/pts/0 status=0 duration=1.2ms: ip link show
+
+	The records of all sessions are also logged with the audit facility
+	and appended to /etc/goes/audit. A background job is recorded, once
+	done, by the cli that started it as well as the commands it runs.
+
+	The session, user and remote are those of the cli as it started. A
+	change of GOES_SESSION, GOES_USER or GOES_REMOTE, e.g. with export,
+	is logged with the audit facility rather than changing the records.
+
+OPTIONS
+	-a	print the records of all sessions from the audit file
+	-n COUNT
+		print at most the last COUNT records
+	-json	print the records as a JSON array`,
+	}
+}
+
+func (Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c Command) Main(args ...string) error {
+	records, err := c.records(args)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		fmt.Println(r.Time.Format("2006-01-02T15:04:05Z07:00"), r)
+	}
+	return nil
+}
+
+func (c Command) JSON(args ...string) (interface{}, error) {
+	return c.records(args)
+}
+
+func (Command) records(args []string) ([]goes.AuditRecord, error) {
+	flag, args := flags.New(args, "-a")
+	parm, args := parms.New(args, "-n")
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%v: unexpected", args)
+	}
+	records := goes.AuditRecords()
+	if flag.ByName["-a"] {
+		var err error
+		if records, err = readFile(goes.AuditFile); err != nil {
+			return nil, err
+		}
+	}
+	if s := parm.ByName["-n"]; len(s) > 0 {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s: invalid COUNT", s)
+		}
+		if n < len(records) {
+			records = records[len(records)-n:]
+		}
+	}
+	if records == nil {
+		records = []goes.AuditRecord{}
+	}
+	return records, nil
+}
+
+func readFile(fn string) ([]goes.AuditRecord, error) {
+	if len(fn) == 0 {
+		return nil, fmt.Errorf("no audit file")
+	}
+	f, err := os.Open(fn)
+	if os.IsNotExist(err) {
+		// nothing audited yet
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []goes.AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var r goes.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", fn, line, err)
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
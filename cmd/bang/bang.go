@@ -19,6 +19,7 @@ import (
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/external/flags"
 	"github.com/platinasystems/goes/external/parms"
+	"github.com/platinasystems/goes/internal/cgroup"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/url"
 )
@@ -39,6 +40,7 @@ var namespaces = []struct {
 
 var parmlist = map[string]struct{}{
 	"-cd":     {},
+	"-cgroup": {},
 	"-chroot": {},
 }
 
@@ -47,7 +49,7 @@ type Command struct{}
 func (Command) String() string { return "!" }
 
 func (Command) Usage() string {
-	return "! COMMAND [-m] [-u] [-i] [-p] [-u] [-cd DIR] [-chroot DIR] [ARGS]... [&]"
+	return "! COMMAND [-m] [-u] [-i] [-p] [-u] [-cd DIR] [-chroot DIR] [-cgroup NAME] [ARGS]..."
 }
 
 func (Command) Apropos() lang.Alt {
@@ -62,8 +64,8 @@ func (Command) Man() lang.Alt {
 DESCRIPTION
 	Sh-bang!
 
-	Command executes in background if last argument ends with '&'.
-	The standard i/o redirections apply.
+	The standard i/o redirections apply. Like any other command, it may
+	be run in the background by terminating it with '&'.
 
 OPTIONS
 	-m		create in new mount namespace
@@ -72,15 +74,15 @@ OPTIONS
 	-p		create in new PID namespace
 	-u		create in new user namespace
 	-cd DIR		change directory to DIR to run command
-	-chroot DIR	change root directory to DIR to run command`,
+	-chroot DIR	change root directory to DIR to run command
+	-cgroup NAME	run command in the cgroup v2 of goes with NAME,
+			e.g. /sys/fs/cgroup/goes/NAME`,
 	}
 }
 
 func (Command) Kind() cmd.Kind { return cmd.DontFork }
 
 func (Command) Main(args ...string) error {
-	var background bool
-
 	opts := args
 	args = []string{}
 	for i := 0; i < len(opts); i++ {
@@ -102,7 +104,8 @@ func (Command) Main(args ...string) error {
 
 	parms, opts := parms.New(opts,
 		"-chroot",
-		"-cd")
+		"-cd",
+		"-cgroup")
 
 	flags, opts := flags.New(opts,
 		"-m",
@@ -115,11 +118,6 @@ func (Command) Main(args ...string) error {
 		return fmt.Errorf("Unexpected %v\n", opts)
 	}
 
-	if n := len(args); args[n-1] == "&" {
-		background = true
-		args = args[:n-1]
-	}
-
 	fp := args[0]
 	var u *neturl.URL
 	if parms.ByName["-chroot"] == "" {
@@ -168,17 +166,17 @@ func (Command) Main(args ...string) error {
 		Unshareflags: unshareFlags,
 	}
 
-	if background {
-		go func() {
-			err := cmd.Run()
-			if err != nil {
-				fmt.Fprintln(os.Stderr, cmd.Args[0], ": ", err)
-			}
-		}()
-		return nil
-	} else {
-		return cmd.Run()
+	if name := parms.ByName["-cgroup"]; len(name) > 0 {
+		cg, err := cgroup.New(name)
+		if err != nil {
+			return err
+		}
+		if err = cg.Start(cmd); err != nil {
+			return err
+		}
+		return cmd.Wait()
 	}
+	return cmd.Run()
 }
 
 func loadNetExec(u *neturl.URL) (execpath, command string, err error) {
@@ -0,0 +1,56 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package bg
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "bg" }
+
+func (*Command) Usage() string { return "bg [%JOB]..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "continue stopped jobs in the background",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Continue each given, or the current, stopped job in the background.`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	for _, spec := range args {
+		j, err := c.g.FindJob(spec)
+		if err != nil {
+			return err
+		}
+		if err = c.g.Resume(j); err != nil {
+			return err
+		}
+		fmt.Printf("[%d] %s &\n", j.Id, j.Text)
+	}
+	return nil
+}
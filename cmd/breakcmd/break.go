@@ -0,0 +1,84 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package breakcmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	IsContinue bool
+}
+
+func (c Command) String() string {
+	if c.IsContinue {
+		return "continue"
+	}
+	return "break"
+}
+
+func (c Command) Usage() string {
+	return c.String() + " [N]"
+}
+
+func (c Command) Apropos() lang.Alt {
+	if c.IsContinue {
+		return lang.Alt{
+			lang.EnUS: "resume the next iteration of a loop",
+		}
+	}
+	return lang.Alt{
+		lang.EnUS: "exit from a loop",
+	}
+}
+
+func (c Command) Man() lang.Alt {
+	if c.IsContinue {
+		return lang.Alt{
+			lang.EnUS: `
+DESCRIPTION
+	Resume the next iteration of the enclosing for, while, or until loop;
+	or with N, that of the Nth enclosing loop.`,
+		}
+	}
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Exit from the enclosing for, while, or until loop; or with N, from N
+	enclosing loops.`,
+	}
+}
+
+func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
+	cl := ls.Cmds[0]
+	if len(cl.Cmds) > 2 {
+		return nil, nil, fmt.Errorf("%s: too many arguments", c)
+	}
+	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		n := 1
+		if _, args := cl.SliceSubst(g.Getenv, g.Cmdsubst); len(args) > 1 {
+			var err error
+			n, err = strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("%s: %s: loop count out of range",
+					c, args[1])
+			}
+		}
+		g.Status = nil
+		return &goes.LoopError{N: n, Continue: c.IsContinue}
+	}
+	return &ls, runfun, nil
+}
+
+func (Command) Main(args ...string) error {
+	return errors.New("internal error")
+}
@@ -0,0 +1,214 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package casecmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+// arm is a list of patterns and the commands run on the first match.
+type arm struct {
+	patterns []shellutils.Word
+	list     []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
+}
+
+func (Command) String() string { return "case" }
+
+func (Command) Usage() string {
+	return "case WORD in [PATTERN [| PATTERN]...) COMMAND ;;]... esac"
+}
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "execute commands selected by pattern",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Executes the commands of the first PATTERN that matches the expanded
+	WORD. Each list of commands is ended by ';;' or 'esac'. The status is
+	that of the last command run, or success if none.
+
+	A PATTERN matches like a file name except that '*' also matches '/'.
+
+		*	any string
+		?	any character
+		[...]	any of the enclosed characters or, with a leading
+			'!', any character not enclosed
+
+	Quoted characters match literally.
+
+EXAMPLES
+	case $(cat /etc/hostname) in
+	bmc*)	echo a BMC ;;
+	sw-*|rtr-*)
+		echo a switch
+		;;
+	*)	echo unknown ;;
+	esac`,
+	}
+}
+
+func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
+	cl := ls.Cmds[0]
+	// case WORD in
+	if len(cl.Cmds) < 3 || cl.Cmds[2].String() != "in" {
+		return nil, nil, errors.New("case: expected `case WORD in'")
+	}
+	word := cl.Cmds[1]
+	cl.Cmds = cl.Cmds[3:]
+	if len(cl.Cmds) > 0 {
+		ls.Cmds[0] = cl
+	} else {
+		ls.Cmds = ls.Cmds[1:]
+	}
+	next := func() error {
+		for len(ls.Cmds) == 0 {
+			newls, err := shellutils.Parse("case>", g.Catline)
+			if err != nil {
+				return err
+			}
+			ls = *newls
+		}
+		cl = ls.Cmds[0]
+		return nil
+	}
+
+	var arms []arm
+	for {
+		if err := next(); err != nil {
+			return nil, nil, err
+		}
+		if len(cl.Cmds) == 0 {
+			return nil, nil, fmt.Errorf("case: unexpected `%s'",
+				cl.Term.String())
+		}
+		if cl.Cmds[0].String() == "esac" {
+			if len(cl.Cmds) > 1 {
+				return nil, nil,
+					errors.New("unexpected text after esac")
+			}
+			break
+		}
+		// PATTERN [| PATTERN]... )
+		var a arm
+		words := cl.Cmds
+		if words[0].String() == "(" {
+			words = words[1:]
+		}
+		for {
+			if len(words) == 0 {
+				if len(a.patterns) == 0 ||
+					cl.Term.String() != "|" {
+					return nil, nil,
+						errors.New("case: expected `)'")
+				}
+				ls.Cmds = ls.Cmds[1:]
+				if err := next(); err != nil {
+					return nil, nil, err
+				}
+				words = cl.Cmds
+				continue
+			}
+			w := words[0]
+			words = words[1:]
+			if w.String() == ")" {
+				break
+			}
+			if len(words) > 0 && words[0].String() != ")" {
+				return nil, nil, fmt.Errorf("case: unexpected `%s'",
+					words[0].String())
+			}
+			a.patterns = append(a.patterns, w)
+		}
+		cl.Cmds = words
+		if len(cl.Cmds) > 0 {
+			ls.Cmds[0] = cl
+		} else {
+			ls.Cmds = ls.Cmds[1:]
+			if cl.Term.String() == ";;" {
+				arms = append(arms, a)
+				continue
+			}
+		}
+		// COMMAND... ;;
+		for {
+			if err := next(); err != nil {
+				return nil, nil, err
+			}
+			if len(cl.Cmds) == 0 {
+				ls.Cmds = ls.Cmds[1:]
+				if cl.Term.String() == ";;" {
+					break
+				}
+				continue
+			}
+			if cl.Cmds[0].String() == "esac" {
+				break
+			}
+			nextls, term, runfun, err := g.ProcessList(ls)
+			if err != nil {
+				return nil, nil, err
+			}
+			a.list = append(a.list, runfun)
+			ls = *nextls
+			if term.String() == ";;" {
+				break
+			}
+		}
+		arms = append(arms, a)
+	}
+	blockfun, err := makeBlockFunc(g, word, arms)
+
+	return &ls, blockfun, err
+}
+
+func runList(pipeline []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	for _, runent := range pipeline {
+		err := runent(stdin, stdout, stderr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func makeBlockFunc(g *goes.Goes, word shellutils.Word, arms []arm) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
+	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		s := strings.Join(word.Fields(g.Getenv, g.Cmdsubst), " ")
+		g.Status = nil
+		for _, a := range arms {
+			for _, p := range a.patterns {
+				match, err := shellutils.Match(p.Pattern(g.Getenv,
+					g.Cmdsubst), s)
+				if err != nil {
+					return err
+				}
+				if match {
+					return runList(a.list, stdin, stdout,
+						stderr)
+				}
+			}
+		}
+		return nil
+	}
+	return runfun, nil
+}
+
+func (Command) Main(args ...string) error {
+	return errors.New("internal error")
+}
@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"os/signal"
+	"strings"
 
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
@@ -17,6 +19,7 @@ import (
 	"github.com/platinasystems/goes/cmd/cli/internal/notliner"
 	"github.com/platinasystems/goes/cmd/resize"
 	"github.com/platinasystems/goes/external/flags"
+	"github.com/platinasystems/goes/external/parms"
 	"github.com/platinasystems/goes/internal/shellutils"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/url"
@@ -42,7 +45,7 @@ type Command struct {
 func (*Command) String() string { return "cli" }
 
 func (*Command) Usage() string {
-	return "cli [-x] [-p PROMPT] [URL]"
+	return "cli [-e | -f] [-n] [-x] [-p PROMPT] [-c COMMAND | URL [ARG]...]"
 }
 
 func (*Command) Apropos() lang.Alt {
@@ -69,8 +72,28 @@ DESCRIPTION
 
 	The '-x' flag enables trace of each interpreted command.
 
+	The '-e' flag exits on the failure of any command as with 'set -e'.
+
+	The '-f' flag continues a script, with trace, after an error that
+	otherwise exits it; see EXIT STATUS.
+
+	The '-n' flag checks the commands without running them and reports
+	syntax errors, unbalanced blocks and unknown commands by line.
+
 	With 'URL', commands are sourced from the reference instead of prompted
-	tty input.
+	tty input. Any following arguments are the script's positional
+	parameters.
+
+	With '-c COMMAND', the given command text is run instead.
+
+HELP
+	At the prompt, TAB completes the last word and '?' lists the
+	candidates for it or, after a space, for the next word, e.g.:
+		ip route ?
+		add             route table entry
+		...
+	Commands and options are listed with their description; parameters
+	with the kind of value that they require.
 
 COMMENTS
 	Hash tag prefaced comments are ignored, e.g.:
@@ -83,8 +106,12 @@ WHITESPACE
 
 			echo hello
 
-	However, the cli doesn't have command blocks so there isn't much
-	reason to indent input for anything other than "here documents".
+	So, the commands of blocks like functions and groups may be indented.
+
+		{
+			ip link
+			ip addr
+		} > /tmp/snap
 
 ESCAPES
 	A COMMAND may extend to multiple lines by escaping the end of
@@ -109,6 +136,119 @@ QUOTATION
 
 		echo 'hello "beautiful world"'
 
+COMMAND SUBSTITUTION
+	The output of a command may replace an argument, or part of one, with
+	either of these forms.
+
+		echo today is $(date)
+		echo today is ` + "`date`" + `
+
+	Unless quoted, the output is split into separate arguments at
+	whitespace. Trailing newlines are removed in either case.
+
+		for dev in $(ls /sys/class/net); do echo $dev; done
+		echo "$(cat /etc/motd)"
+
+	Substitutions may be nested and run in the current context.
+
+EXIT STATUS
+	The exit status of the last command or pipeline is available as $?;
+	it is 0 on success, the exit code of a failed command, or 128 plus
+	the number of a fatal signal.
+
+		grep -q eth0 /proc/net/dev
+		echo $?
+
+	The status of a pipeline is that of its last command, or with
+	'set -o pipefail', that of its last failing command.
+
+	Unless run with '-f', a script exits on the error of a command run
+	in-process, e.g. cd, or not found, rather than just the failed exit
+	status of another, again other than the condition of an if, while or
+	until, or a command followed by '&&' or '||'.
+
+		cd /nonexistent
+		echo not reached
+
+	With 'set -e', a script exits on the first failing command other than
+	the condition of an if, while or until, or a command followed by
+	'&&' or '||'. This may be limited to a few critical steps.
+
+		set -e
+		ip link set eth0 up
+		set +e
+
+POSITIONAL PARAMETERS
+	The arguments of a function, or of a script given to 'cli' or
+	'source', are available as $1 through $9, or ${N} for others. $# is
+	their count; "$@" expands to each of them as separate arguments and
+	$* to all of them as one. 'shift' discards the first, and 'local'
+	sets variables until the function or script returns.
+
+		function up {
+			local dev=$1
+			shift
+			ip link set $dev up "$@"
+		}
+
+JOBS
+	A list terminated by '&' is run in the background by a separate cli
+	with a copy of the current variables but not functions. Its process
+	id is available as $!.
+
+		ping -c 100 10.0.0.1 > ping.log &
+		wait $!
+
+	At an interactive prompt, each foreground pipeline is a job that may
+	be stopped with ^Z. These commands list and manipulate jobs.
+
+		jobs		list background and stopped jobs
+		fg [%JOB]	continue a job in the foreground
+		bg [%JOB]	continue a stopped job in the background
+		wait [%JOB]...	wait for jobs to finish
+		kill %JOB	signal every process of a job
+
+	A job is given by its number, e.g. %1; %% or %+ for the current
+	job; %- for the previous; or %TEXT for the last job beginning with
+	TEXT. The completion of background jobs is reported before the next
+	prompt.
+
+GROUPS
+	A list within braces is run in the current context, so that variable
+	assignments and directory changes persist. The closing brace must
+	follow a terminator or newline.
+
+		{ ip link; ip addr; } > /tmp/snap
+
+	A list within parentheses is run by a subshell, a separate cli with a
+	copy of the current variables but not functions. Its changes don't
+	affect the current context.
+
+		( cd /tmp; ls ) | grep log
+
+	Redirections following either group apply to all of its commands.
+
+HISTORY
+	Interactive command lines are saved to $HISTFILE, or by default,
+	~/.goes_history for recall in later sessions. $HISTSIZE is the
+	number of lines kept, 500 by default.
+
+	At the prompt, ^P and ^N recall the previous and next lines; ^R
+	searches backward for a line with the text typed thereafter.
+
+AUDIT
+	Each command of an interactive cli, or of a telnet or ssh session, is
+	logged with the audit facility along with the session id, user,
+	remote address, tty, exit status and duration. The 'audit' command
+	lists them.
+
+ALIASES
+	A command may be replaced by another with leading arguments.
+
+		alias rt='ip route show table'
+		rt main
+		unalias rt
+
 SPECIAL CHARACTERS
 .	The command may encode these special characters.
 
@@ -147,6 +287,23 @@ OPTIONS
 
 	< URL	Redirect stdin from URL.
 
+	2> URL
+	2>> URL
+		Redirect or append stderr to URL.
+
+	&> URL
+	&>> URL
+		Redirect or append both stdout and stderr to URL.
+
+	2>&1	Redirect stderr to stdout, e.g. with a pipe,
+			cmd 2>&1 | grep -i error
+
+	1>&2, >&2
+		Redirect stdout to stderr.
+
+	The numbered redirections apply after the others and in order, so
+	'cmd > log 2>&1' writes both stdout and stderr to log.
+
 	<<[-] LABEL
 		Read command script upto LABEL as stdin. If LABEL is prefaced
 		by '-', the leading whitespace is trimmed from each line.
@@ -165,7 +322,14 @@ PIPES
 
 		cat <<- EOF | wc -l > lines.txt
 			...
-		EOF`,
+		EOF
+
+	Functions, blocks and other in-process commands within a pipeline run
+	concurrently with the other commands. Like other shells, each but the
+	last has a copy of the current context, so its changes don't persist.
+
+		for dev in $(ls /sys/class/net); do ip link show $dev; done |
+		grep UP`,
 	}
 }
 
@@ -198,17 +362,25 @@ func (c *Command) Main(args ...string) error {
 		panic("cli's goes is nil")
 	}
 
+	// unless given, the standard files are those of the process when run
 	if c.Stdin == nil {
 		c.Stdin = os.Stdin
+		defer func() { c.Stdin = nil }()
 	}
 	if c.Stdout == nil {
 		c.Stdout = os.Stdout
+		defer func() { c.Stdout = nil }()
 	}
 	if c.Stderr == nil {
 		c.Stderr = os.Stderr
+		defer func() { c.Stderr = nil }()
 	}
 	signal.Notify(goes.IntSig, os.Interrupt)
 
+	// a sourced script resumes the prompter of the sourcing cli
+	prompter := c.prompter
+	defer func() { c.prompter = prompter }()
+
 	defer func() {
 		for _, name := range c.g.Names() {
 			v := c.g.ByName[name]
@@ -224,21 +396,43 @@ func (c *Command) Main(args ...string) error {
 		}
 	}()
 
-	flag, args := flags.New(args, "-f", "-x", "-", "-no-liner")
+	// arguments following the URL are positional parameters
+	var params []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+		} else if !strings.HasPrefix(args[i], "-") {
+			params = args[i+1:]
+			args = args[:i+1]
+			break
+		}
+	}
+	parm, args := parms.New(args, "-c")
+	flag, args := flags.New(args, "-e", "-f", "-n", "-x", "-", "-no-liner")
+	if flag.ByName["-n"] {
+		return c.lint(parm.ByName["-c"], flag.ByName["-"], args)
+	}
 	switch len(args) {
 	case 0:
 		switch {
+		case len(parm.ByName["-c"]) > 0:
+			c.prompter = notliner.New(strings.NewReader(parm.ByName["-c"]),
+				nil)
+			isScript = true
 		case flag.ByName["-"]:
 			c.prompter = notliner.New(c.Stdin, nil)
 			isScript = true
 		case flag.ByName["-no-liner"]:
 			c.prompter = notliner.New(c.Stdin, c.Stdout)
+			goes.EnableAudit()
 		default:
 			if _, found := c.g.ByName["resize"]; !found {
 				c.g.ByName["resize"] = resize.Command{}
 			}
 			c.prompter = liner.New(c.g)
 			defer c.prompter.Close()
+			c.g.EnableJobControl()
+			goes.EnableAudit()
 		}
 	case 1:
 		script, err := url.Open(args[0])
@@ -249,6 +443,12 @@ func (c *Command) Main(args ...string) error {
 		c.prompter = notliner.New(script, nil)
 		defer c.prompter.Close()
 		isScript = true
+		// without arguments, a script has those of its caller
+		if len(params) == 0 {
+			params = c.g.Args()
+		}
+		c.g.PushFrame(params)
+		defer c.g.PopFrame()
 	default:
 		return fmt.Errorf("%v: unexpected", args[1:])
 	}
@@ -256,6 +456,12 @@ func (c *Command) Main(args ...string) error {
 	if flag.ByName["-f"] && c.g.Verbosity < goes.VerboseVerify {
 		c.g.Verbosity = goes.VerboseVerify
 	}
+	if flag.ByName["-e"] {
+		c.g.ErrExit = true
+	}
+	errabort := c.g.ErrAbort
+	c.g.ErrAbort = isScript && !flag.ByName["-f"]
+	defer func() { c.g.ErrAbort = errabort }()
 	if c.g.Catline == nil {
 		c.g.Catline = c
 	}
@@ -266,6 +472,11 @@ readCommandLoop:
 			fmt.Println("\nCommand interrupted")
 		default:
 		}
+		if c.g.JobControl {
+			for _, j := range c.g.ReapJobs() {
+				fmt.Fprintln(c.Stderr, j)
+			}
+		}
 		prompt := c.Prompt
 		if len(prompt) == 0 {
 			prompt = fmt.Sprint(c.g, "> ")
@@ -278,6 +489,10 @@ readCommandLoop:
 		cl, err := shellutils.Parse(prompt, c.g.Catline)
 		if err != nil {
 			if err == io.EOF {
+				if len(parm.ByName["-c"]) > 0 {
+					// a subshell exits with its last status
+					os.Exit(goes.ExitStatus(c.g.Status))
+				}
 				return nil
 			}
 			fmt.Fprintln(c.Stderr, err)
@@ -288,6 +503,43 @@ readCommandLoop:
 		}
 		err = c.runList(*cl, flag, isScript)
 		if err != nil {
+			var exit *goes.ExitError
+			if errors.As(err, &exit) {
+				if len(parm.ByName["-c"]) > 0 {
+					if !isStatus(exit.Err) {
+						fmt.Fprintln(c.Stderr, exit.Err)
+					}
+					os.Exit(goes.ExitStatus(exit.Err))
+				}
+				return exit.Err
+			}
+			var stopped *goes.StoppedError
+			if errors.As(err, &stopped) {
+				fmt.Fprintln(c.Stderr)
+				fmt.Fprintln(c.Stderr, stopped)
+				continue readCommandLoop
+			}
+			var ret *goes.ReturnError
+			if isScript && errors.As(err, &ret) {
+				// a sourced script ends with return
+				if len(parm.ByName["-c"]) > 0 {
+					os.Exit(ret.Status)
+				}
+				c.g.Return(err)
+				return c.g.Status
+			}
+			if errors.Is(err, goes.ErrBrokenPipe) {
+				// the reader of a script's output is gone
+				if isScript {
+					return nil
+				}
+				continue readCommandLoop
+			}
+			var loop *goes.LoopError
+			if errors.As(err, &loop) || errors.As(err, &ret) {
+				fmt.Fprintln(c.Stderr, err)
+				continue readCommandLoop
+			}
 			if isScript && !flag.ByName["-f"] {
 				return err
 			} else {
@@ -297,6 +549,35 @@ readCommandLoop:
 	}
 }
 
+// lint reports the problems of the script or command text on stderr.
+func (c *Command) lint(text string, stdin bool, args []string) error {
+	name, r := "-c", io.Reader(strings.NewReader(text))
+	switch {
+	case len(args) > 1:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	case len(args) == 1:
+		script, err := url.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer script.Close()
+		name, r = args[0], script
+	case stdin || len(text) == 0:
+		name, r = "-", c.Stdin
+	}
+	diags, err := c.g.Lint(name, r)
+	if err != nil {
+		return err
+	}
+	for _, d := range diags {
+		fmt.Fprintln(c.Stderr, d)
+	}
+	if len(diags) > 0 {
+		return goes.StatusError(1)
+	}
+	return nil
+}
+
 func (c *Command) runList(ls shellutils.List, flag *flags.Flags, isScript bool) (err error) {
 	// loop for each pipeline in command list
 	for len(ls.Cmds) != 0 {
@@ -315,3 +596,13 @@ func (c *Command) runList(ls shellutils.List, flag *flags.Flags, isScript bool)
 	}
 	return nil
 }
+
+// isStatus reports whether the error is just the exit status of a command,
+// which isn't reported again as that of the script.
+func isStatus(err error) bool {
+	var (
+		status goes.StatusError
+		ee     *exec.ExitError
+	)
+	return errors.As(err, &status) || errors.As(err, &ee)
+}
@@ -0,0 +1,67 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package cli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/cmd/cd"
+	"github.com/platinasystems/goes/cmd/cli"
+	"github.com/platinasystems/goes/cmd/echo"
+)
+
+// TestScriptStops checks that a script ends with the error of a command
+// unless run with -f.
+func TestScriptStops(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	script, fn := filepath.Join(dir, "script"), filepath.Join(dir, "out")
+	err = ioutil.WriteFile(script, []byte("echo one > "+fn+"\n"+
+		"cd "+filepath.Join(dir, "nonexistent")+"\n"+
+		"echo after-cd >> "+fn+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		args []string
+		want string
+		fail bool
+	}{
+		{[]string{"cli", script}, "one\n", true},
+		{[]string{"cli", "-f", script}, "one\nafter-cd\n", false},
+	} {
+		g := &goes.Goes{
+			NAME: "goes",
+			ByName: map[string]cmd.Cmd{
+				"cd":   &cd.Command{},
+				"cli":  &cli.Command{},
+				"echo": echo.Command{},
+			},
+		}
+		err := g.Main(tc.args...)
+		if failed := err != nil; failed != tc.fail {
+			t.Errorf("%v: %v", tc.args, err)
+		}
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Error(err)
+		} else if s := string(b); s != tc.want {
+			t.Errorf("%v: %q, want %q", tc.args, s, tc.want)
+		}
+	}
+}
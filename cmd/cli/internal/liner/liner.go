@@ -11,6 +11,8 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"unsafe"
@@ -27,11 +29,16 @@ import (
 
 const woliner = false
 
+// DefaultHistorySize is the number of saved lines without $HISTSIZE.
+const DefaultHistorySize = 500
+
 type Liner struct {
 	history struct {
 		buf   *bytes.Buffer
 		lines []string
-		i     int
+		size  int
+		// file is the per-user history, e.g. ~/.goes_history
+		file string
 	}
 	fallback *notliner.Prompter
 	goes     *goes.Goes
@@ -41,7 +48,22 @@ type Liner struct {
 func New(g *goes.Goes) *Liner {
 	l := new(Liner)
 	l.history.buf = new(bytes.Buffer)
-	l.history.lines = make([]string, 0, 1<<6)
+	l.history.size = DefaultHistorySize
+	if s := g.Getenv("HISTSIZE"); len(s) > 0 {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+			l.history.size = n
+		}
+	}
+	if l.history.size > liner.HistoryLimit {
+		l.history.size = liner.HistoryLimit
+	}
+	l.history.file = g.Getenv("HISTFILE")
+	if len(l.history.file) == 0 {
+		if home := g.Getenv("HOME"); len(home) > 0 {
+			l.history.file = filepath.Join(home, ".goes_history")
+		}
+	}
+	l.loadHistory()
 	if woliner {
 		l.fallback = notliner.New(os.Stdin, os.Stdout)
 	}
@@ -49,6 +71,73 @@ func New(g *goes.Goes) *Liner {
 	return l
 }
 
+// loadHistory reads the last lines of the history file, which is rewritten
+// if it has grown past twice the history size.
+func (l *Liner) loadHistory() {
+	if len(l.history.file) == 0 {
+		return
+	}
+	f, err := os.Open(l.history.file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		l.history.lines = append(l.history.lines, scanner.Text())
+		if len(l.history.lines) > l.history.size {
+			l.history.lines = l.history.lines[1:]
+		}
+		n++
+	}
+	if n > 2*l.history.size {
+		l.saveHistory()
+	}
+}
+
+// saveHistory rewrites the history file with the current lines.
+func (l *Liner) saveHistory() {
+	tmp := l.history.file + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	for _, line := range l.history.lines {
+		fmt.Fprintln(f, line)
+	}
+	if f.Close() == nil {
+		os.Rename(tmp, l.history.file)
+	} else {
+		os.Remove(tmp)
+	}
+}
+
+// addHistory appends a non-blank line to the history and its file unless
+// it repeats the last.
+func (l *Liner) addHistory(line string) {
+	if l.history.size == 0 || len(strings.TrimSpace(line)) == 0 {
+		return
+	}
+	if n := len(l.history.lines); n > 0 && l.history.lines[n-1] == line {
+		return
+	}
+	l.history.lines = append(l.history.lines, line)
+	if len(l.history.lines) > l.history.size {
+		l.history.lines = l.history.lines[1:]
+	}
+	if len(l.history.file) == 0 {
+		return
+	}
+	f, err := os.OpenFile(l.history.file,
+		os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(f, line)
+	f.Close()
+}
+
 func (l *Liner) Close() {
 }
 
@@ -88,7 +177,8 @@ func (l *Liner) complete(line string) (lines []string) {
 	return
 }
 
-// Prints the best available help text for the last arg of line
+// Prints the candidates for the last or, after a space, next arg of line
+// with their apropos; or, failing that, the best available help text.
 func (l *Liner) help(line string) {
 	pl := pizza.New("|")
 	defer pl.Reset()
@@ -97,10 +187,32 @@ func (l *Liner) help(line string) {
 		return
 	}
 	args := pl.Slices[len(pl.Slices)-1]
-	if len(args) == 0 || pl.More {
+	if pl.More {
 		fmt.Println("Enter command.")
-	} else {
-		l.goes.Main(append([]string{"help"}, args...)...)
+		return
+	}
+	words := args
+	if len(args) == 0 || strings.HasSuffix(line, " ") {
+		words = append(words, "")
+	}
+	hints := l.goes.Hints(words...)
+	if len(hints) == 0 {
+		if len(args) > 0 {
+			l.goes.Main(append([]string{"help"}, args...)...)
+		}
+		return
+	}
+	for _, hint := range hints {
+		switch {
+		case len(hint.Apropos) == 0:
+			fmt.Println(hint.Word)
+		case len(hint.Word) < 16:
+			fmt.Print(hint.Word, "                "[len(hint.Word):])
+			fmt.Println(hint.Apropos)
+		default:
+			fmt.Print(hint.Word, "\n\t\t")
+			fmt.Println(hint.Apropos)
+		}
 	}
 }
 
@@ -162,18 +274,8 @@ func (l *Liner) Prompt(prompt string) (string, error) {
 
 	if len(l.history.lines) > 0 {
 		l.history.buf.Reset()
-		if len(l.history.lines) < cap(l.history.lines) {
-			for i := 0; i < l.history.i; i++ {
-				fmt.Fprintln(l.history.buf, l.history.lines[i])
-			}
-		} else {
-			for i := l.history.i + 1; ; i++ {
-				i &= cap(l.history.lines) - 1
-				if i == l.history.i {
-					break
-				}
-				fmt.Fprintln(l.history.buf, l.history.lines[i])
-			}
+		for _, line := range l.history.lines {
+			fmt.Fprintln(l.history.buf, line)
 		}
 		l.s.ReadHistory(l.history.buf)
 	}
@@ -181,13 +283,7 @@ func (l *Liner) Prompt(prompt string) (string, error) {
 	line, err := l.s.Prompt(prompt)
 
 	if err == nil {
-		if len(l.history.lines) < cap(l.history.lines) {
-			l.history.lines = append(l.history.lines, line)
-		} else {
-			l.history.lines[l.history.i] = line
-		}
-		l.history.i++
-		l.history.i &= cap(l.history.lines) - 1
+		l.addHistory(line)
 	} else if err == liner.ErrNotTerminalOutput {
 		l.fallback = notliner.New(os.Stdin, os.Stdout)
 		line, err = l.fallback.Prompt(prompt)
@@ -19,7 +19,10 @@ type Cmd interface {
 	Complete(...string) []string
 	Goes(*goes.Goes)
 	Help(...string) string
+	JSON(...string) (interface{}, error)
 	Kind() Kind
+	MainContext(context.Context, ...string) error
 	Man() lang.Alt
+	Options() options.Options
 	*/
 }
@@ -5,12 +5,15 @@
 package daemons
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/external/atsock"
+	"github.com/platinasystems/goes/external/options"
 	"github.com/platinasystems/goes/lang"
 )
 
@@ -22,6 +25,7 @@ var Admin = &goes.Goes{
 	},
 	ByName: map[string]cmd.Cmd{
 		"log":     Log{},
+		"ready":   Ready{},
 		"restart": Restart{},
 		"start":   Start{},
 		"status":  Status{},
@@ -32,6 +36,7 @@ var Admin = &goes.Goes{
 var empty = struct{}{}
 
 type Log struct{}
+type Ready struct{}
 type Restart struct{}
 type Status struct{}
 type Start struct{}
@@ -62,10 +67,43 @@ func (Log) Main(args ...string) error {
 	return err
 }
 
+func (Ready) String() string { return "ready" }
+
+func (Ready) Usage() string {
+	return "daemon ready [NAME]"
+}
+
+func (Ready) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "notify the readiness of a daemon",
+	}
+}
+
+func (Ready) Main(args ...string) error {
+	var name string
+	switch len(args) {
+	case 0:
+		name = os.Getenv(DaemonEnv)
+		if len(name) == 0 {
+			return fmt.Errorf("NAME: missing")
+		}
+	case 1:
+		name = args[0]
+	default:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	cl, err := atsock.NewRpcClient(sockname())
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+	return cl.Call("Daemons.Ready", name, &empty)
+}
+
 func (Restart) String() string { return "restart" }
 
 func (Restart) Usage() string {
-	return "daemon restart [PID]..."
+	return "daemon restart [PID|NAME]..."
 }
 
 func (Restart) Apropos() lang.Alt {
@@ -74,6 +112,17 @@ func (Restart) Apropos() lang.Alt {
 	}
 }
 
+func (Restart) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Stop, as with "daemon stop", then start the daemons with the given
+	PID or NAME, including those that have failed, or without either,
+	all. All are started again as by goes-daemons, each once those it
+	requires or is after are ready.`,
+	}
+}
+
 func (Restart) Main(args ...string) error {
 	cl, err := atsock.NewRpcClient(sockname())
 	if err != nil {
@@ -107,10 +156,15 @@ func (Start) Main(args ...string) error {
 	return cl.Call("Daemons.Start", args, &empty)
 }
 
+var statusOpts = options.Options{
+	{Name: "-watch", Type: options.Duration, Arg: "INTERVAL",
+		Help: "Repeat every INTERVAL seconds or duration, e.g. 5 or 1m"},
+}
+
 func (Status) String() string { return "status" }
 
 func (Status) Usage() string {
-	return "daemon status"
+	return statusOpts.Usage("daemon status")
 }
 
 func (Status) Apropos() lang.Alt {
@@ -119,23 +173,85 @@ func (Status) Apropos() lang.Alt {
 	}
 }
 
-func (Status) Main(args ...string) error {
-	var s string
+func (Status) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Show the state, uptime, restarts, resident memory, CPU time, open
+	files and threads of each supervised daemon along with the memory
+	and processes of its cgroup, e.g. /sys/fs/cgroup/goes/vnetd, and
+	their limits. The same are published to the redis "daemons" hash as
+	NAME.FIELD, e.g. vnetd.rss and vnetd.cgroup.oom_kills
+
+	The state is running, restarting, or failed. A daemon is restarting
+	while it waits out the backoff of its restart policy; its uptime is
+	then that until its next start, e.g. "in 4s", and its next_start
+	field is the time of that start.
+
+` + statusOpts.Man() + `
+	-json	Print the status of each daemon as a JSON array.`,
+	}
+}
+
+func (Status) Options() options.Options { return statusOpts }
+
+func (c Status) Main(args ...string) error {
+	return c.MainContext(context.Background(), args...)
+}
+
+func (Status) MainContext(ctx context.Context, args ...string) error {
+	opt, args, err := statusOpts.Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	interval := opt.Duration("-watch")
+	if opt.IsSet("-watch") && interval <= 0 {
+		return fmt.Errorf("%v: invalid interval", interval)
+	}
 	cl, err := atsock.NewRpcClient(sockname())
 	if err != nil {
 		return err
 	}
 	defer cl.Close()
-	if err = cl.Call("Daemons.List", struct{}{}, &s); err == nil {
-		os.Stdout.WriteString(s)
+	for {
+		var info []Info
+		if err = cl.Call("Daemons.Status", struct{}{}, &info); err != nil {
+			return err
+		}
+		Fprint(os.Stdout, info)
+		if interval == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+		fmt.Println()
 	}
-	return err
+}
+
+func (Status) JSON(args ...string) (interface{}, error) {
+	var info []Info
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%v: unexpected", args)
+	}
+	cl, err := atsock.NewRpcClient(sockname())
+	if err != nil {
+		return nil, err
+	}
+	defer cl.Close()
+	err = cl.Call("Daemons.Status", struct{}{}, &info)
+	return info, err
 }
 
 func (Stop) String() string { return "stop" }
 
 func (Stop) Usage() string {
-	return "daemon stop [PID]..."
+	return "daemon stop [PID|NAME]..."
 }
 
 func (Stop) Apropos() lang.Alt {
@@ -144,6 +260,20 @@ func (Stop) Apropos() lang.Alt {
 	}
 }
 
+func (Stop) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Stop the daemons with the given PID or NAME or, without either, all
+	in the reverse of their dependency order along with goes-daemons.
+
+	Each is stopped in turn by running its stop command, if any, then
+	sending SIGTERM and, if it hasn't exited within its stop timeout,
+	SIGKILL, which is logged. A stopped daemon isn't restarted by its
+	policy.`,
+	}
+}
+
 func (Stop) Main(args ...string) error {
 	cl, err := atsock.NewRpcClient(sockname())
 	if err != nil {
@@ -0,0 +1,130 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/platinasystems/goes/external/log"
+	"github.com/platinasystems/goes/internal/cgroup"
+)
+
+// EventsInterval is that of polling the cgroup events of each daemon for
+// those of its limits.
+var EventsInterval = time.Second
+
+// limits are the cgroup files of the Manifest Limits with the defaults
+// that are restored for restarts without them.
+var limits = [][2]string{
+	{"memory.max", "max"},
+	{"cpu.weight", "100"},
+	{"pids.max", "max"},
+}
+
+func isLimit(file string) bool {
+	for _, limit := range limits {
+		if file == limit[0] {
+			return true
+		}
+	}
+	return false
+}
+
+func validLimit(file, value string) bool {
+	switch file {
+	case "memory.max":
+		if value == "max" {
+			return true
+		}
+		if n := len(value); n > 1 &&
+			strings.IndexByte("KMGT", value[n-1]) >= 0 {
+			value = value[:n-1]
+		}
+		_, err := strconv.ParseUint(value, 10, 64)
+		return err == nil
+	case "cpu.weight":
+		n, err := strconv.ParseUint(value, 10, 16)
+		return err == nil && n >= 1 && n <= 10000
+	case "pids.max":
+		if value == "max" {
+			return true
+		}
+		_, err := strconv.ParseUint(value, 10, 64)
+		return err == nil
+	}
+	return false
+}
+
+// cgroup makes, if necessary, the group of the daemon and sets its limits.
+// The group is kept for restarts of the daemon.
+func (m *Manifest) cgroup() (cgroup.Cgroup, error) {
+	cg, err := cgroup.New(m.String())
+	if err != nil {
+		return "", err
+	}
+	for _, limit := range limits {
+		file, value := limit[0], m.Limits[limit[0]]
+		if _, err := cg.Get(file); err != nil {
+			if len(value) > 0 {
+				log.Print("daemon", "err", m, ": ", file,
+					": unavailable")
+			}
+			continue
+		}
+		if len(value) == 0 {
+			value = limit[1]
+		}
+		if err := cg.Set(file, value); err != nil {
+			log.Print("daemon", "err", m, ": ", file, ": ", err)
+		}
+	}
+	return cg, nil
+}
+
+// watch the events of the daemon's group, logging those of its limits
+// until it has exited.
+func watch(m *Manifest, cg cgroup.Cgroup, exited <-chan struct{}) {
+	counts := make(map[string]uint64)
+	poll := func(report bool) {
+		for _, file := range []string{"memory.events", "pids.events"} {
+			events, err := cg.Events(file)
+			if err != nil {
+				continue
+			}
+			keys := make([]string, 0, len(events))
+			for k := range events {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			controller := strings.TrimSuffix(file, ".events")
+			for _, k := range keys {
+				key := controller + "." + k
+				n, prev := events[k], counts[key]
+				counts[key] = n
+				if report && n > prev && k != "low" {
+					log.Print("daemon", "err", m, ": ",
+						controller, " ", k, " events: ",
+						n-prev)
+				}
+			}
+		}
+	}
+	poll(false)
+	t := time.NewTicker(EventsInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			poll(true)
+		case <-exited:
+			// the last, e.g. oom_kill
+			poll(true)
+			return
+		}
+	}
+}
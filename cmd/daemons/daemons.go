@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +19,7 @@ import (
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/external/atsock"
 	"github.com/platinasystems/goes/external/log"
+	"github.com/platinasystems/goes/internal/cgroup"
 	"github.com/platinasystems/goes/internal/prog"
 )
 
@@ -29,8 +31,13 @@ type Daemons struct {
 	pids  []int
 	log   daemonLog
 
-	cmdsByPid map[int]*exec.Cmd
-	stopping  bool
+	// manifests are those started by startAll.
+	manifests      []*Manifest
+	cmdsByPid      map[int]*exec.Cmd
+	manifestsByPid map[int]*Manifest
+	units          map[string]*unit
+	states         map[*Manifest]*state
+	stopping       bool
 }
 
 func sockname() string {
@@ -40,15 +47,75 @@ func sockname() string {
 func (d *Daemons) init() {
 	d.done = make(chan struct{})
 	d.cmdsByPid = make(map[int]*exec.Cmd)
+	d.manifestsByPid = make(map[int]*Manifest)
+	d.units = make(map[string]*unit)
+	d.states = make(map[*Manifest]*state)
 	d.log.init()
 	log.Tee(&d.log)
 
 }
 
-func (d *Daemons) start(restarts int, args ...string) {
-	if len(args) < 1 {
+// startAll starts each daemon once those that it's after or requires are
+// ready. Those without such pending dependencies start in turn, in the
+// declared order; the others wait for theirs concurrently.
+func (d *Daemons) startAll(manifests []*Manifest) {
+	list, errs := order(manifests)
+	for _, err := range errs {
+		log.Print("daemon", "err", err)
+	}
+	d.mutex.Lock()
+	d.manifests = manifests
+	for _, m := range list {
+		d.units[m.String()] = newUnit()
+	}
+	d.mutex.Unlock()
+	for _, m := range list {
+		if d.pending(m) {
+			go d.startAfter(m)
+		} else {
+			d.startAfter(m)
+		}
+	}
+}
+
+// pending reports whether any daemon that the manifest is after or
+// requires isn't yet ready or failed.
+func (d *Daemons) pending(m *Manifest) bool {
+	for _, name := range append(m.Requires, m.After...) {
+		if dep := d.unit(name); dep != nil && !dep.isDone() {
+			return true
+		}
+	}
+	return false
+}
+
+// startAfter starts the daemon once those that it's after or requires are
+// ready.
+func (d *Daemons) startAfter(m *Manifest) {
+	for _, name := range append(m.Requires, m.After...) {
+		dep := d.unit(name)
+		if dep == nil {
+			continue
+		}
+		select {
+		case <-dep.done:
+		case <-d.done:
+			return
+		}
+		if !dep.ok {
+			log.Print("daemon", "err", m, ": ", name, ": not ready")
+			d.unit(m.String()).set(false)
+			return
+		}
+	}
+	d.start(m)
+}
+
+func (d *Daemons) start(m *Manifest) {
+	if len(m.Args) < 1 {
 		return
 	}
+	args := m.Args
 	rout, wout, err := os.Pipe()
 	defer func(cs string) {
 		if err != nil {
@@ -67,55 +134,169 @@ func (d *Daemons) start(restarts int, args ...string) {
 	p.Stdout = wout
 	p.Stderr = werr
 	p.Dir = "/"
-	p.Env = prog.DaemonEnv()
+	p.Env = m.env()
+	cg, cgerr := m.cgroup()
+	if cgerr != nil && len(m.Limits) > 0 {
+		log.Print("daemon", "err", m, ": ", cgerr)
+	}
 
-	if err = p.Start(); err != nil {
+	d.mutex.Lock()
+	if d.stopping {
+		d.mutex.Unlock()
+		return
+	}
+	if cgerr == nil {
+		err = cg.Start(p)
+	} else {
+		err = p.Start()
+	}
+	if err == nil {
+		d.pids = append(d.pids, p.Process.Pid)
+		d.cmdsByPid[p.Process.Pid] = p
+		d.manifestsByPid[p.Process.Pid] = m
+	}
+	d.mutex.Unlock()
+	if err != nil {
 		return
 	}
 	log.Print("daemon", "info", "running ", p.Process.Pid, " ", args)
+	exited := make(chan struct{})
+	if cgerr == nil {
+		go watch(m, cg, exited)
+	}
+	if u := d.unit(m.String()); u != nil && !u.isDone() {
+		go u.await(m)
+	}
 	id := fmt.Sprintf("%s.%s[%d]", prog.Base(), args[0], p.Process.Pid)
-	d.mutex.Lock()
-	d.pids = append(d.pids, p.Process.Pid)
-	d.cmdsByPid[p.Process.Pid] = p
-	d.mutex.Unlock()
 	go log.LinesFrom(rout, id, "info")
 	go log.LinesFrom(rerr, id, "err")
-	go func(p *exec.Cmd, wout, werr *os.File, args ...string) {
-		if err := p.Wait(); err != nil {
+	go func(p *exec.Cmd, wout, werr *os.File) {
+		err := p.Wait()
+		if err != nil {
 			fmt.Fprintln(werr, err)
 		} else {
 			fmt.Fprintln(wout, "done")
 		}
+		close(exited)
 		if d.cmd(p.Process.Pid) != nil {
 			d.del(p.Process.Pid)
-			if restarts == RestartLimit {
-				if RestartLimit != 0 {
-					fmt.Fprintln(werr, "to many restarts")
-				}
-			} else {
-				fmt.Fprintln(werr, "restart")
-				defer d.start(restarts+1, args...)
-			}
+			d.exited(m, p.ProcessState, err)
 		}
 		wout.Sync()
 		werr.Sync()
 		wout.Close()
 		werr.Close()
-	}(p, wout, werr, args...)
+	}(p, wout, werr)
 }
 
 func (d *Daemons) List(args struct{}, reply *string) error {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+	var info []Info
+	if err := d.Status(args, &info); err != nil {
+		return err
+	}
 	buf := &bytes.Buffer{}
-	for _, pid := range d.pids {
-		p := d.cmdsByPid[pid]
-		fmt.Fprintf(buf, "%d: %v\n", pid, p.Args)
+	for _, i := range info {
+		if i.Pid == 0 {
+			fmt.Fprint(buf, "-")
+		} else {
+			fmt.Fprint(buf, i.Pid)
+		}
+		fmt.Fprintf(buf, ": %v", i.Args)
+		if i.State != "running" {
+			fmt.Fprint(buf, " ", i.State)
+		}
+		if i.NextStart != nil {
+			fmt.Fprint(buf, " next-start=",
+				i.NextStart.Format(time.RFC3339))
+		}
+		if i.Restarts > 0 {
+			fmt.Fprintf(buf, " restarts=%d last-restart=%s",
+				i.Restarts, i.LastRestart.Format(time.RFC3339))
+		}
+		if len(i.LastExit) > 0 {
+			fmt.Fprintf(buf, " last-exit=%q", i.LastExit)
+		}
+		fmt.Fprintln(buf)
 	}
 	*reply = buf.String()
 	return nil
 }
 
+// Info is the JSON object of a running, restarting, or failed daemon.
+type Info struct {
+	Name        string        `json:"name"`
+	Pid         int           `json:"pid"`
+	Args        []string      `json:"args"`
+	State       string        `json:"state"`
+	Uptime      time.Duration `json:"uptime"`
+	Restarts    int           `json:"restarts"`
+	LastExit    string        `json:"last_exit,omitempty"`
+	LastRestart time.Time     `json:"last_restart"`
+	RSS         uint64        `json:"rss"`
+	CPU         time.Duration `json:"cpu"`
+	FDs         int           `json:"fds"`
+	Threads     int           `json:"threads"`
+	// NextStart is that of a restarting daemon.
+	NextStart *time.Time `json:"next_start,omitempty"`
+	// Cgroup is the accounting of the daemon's group, if any.
+	Cgroup *cgroup.Stat `json:"cgroup,omitempty"`
+}
+
+func (d *Daemons) Status(args struct{}, reply *[]Info) error {
+	d.mutex.Lock()
+	info := make([]Info, 0, len(d.pids))
+	for _, pid := range d.pids {
+		m := d.manifestsByPid[pid]
+		st, found := d.states[m]
+		if !found {
+			st = &state{}
+		}
+		info = append(info, Info{
+			Name:        m.String(),
+			Pid:         pid,
+			Args:        d.cmdsByPid[pid].Args,
+			State:       "running",
+			Restarts:    st.restarts,
+			LastExit:    st.lastExit,
+			LastRestart: st.lastRestart,
+		})
+	}
+	running := len(info)
+	for m, st := range d.states {
+		i := Info{
+			Name:        m.String(),
+			Args:        m.Args,
+			Restarts:    st.restarts,
+			LastExit:    st.lastExit,
+			LastRestart: st.lastRestart,
+		}
+		switch {
+		case st.failed:
+			i.State = "failed"
+		case !st.nextStart.IsZero():
+			i.State = "restarting"
+			next := st.nextStart
+			i.NextStart = &next
+		default:
+			continue
+		}
+		info = append(info, i)
+	}
+	d.mutex.Unlock()
+	// those running are in start order, then the rest by name
+	rest := info[running:]
+	sort.Slice(rest, func(i, j int) bool {
+		return rest[i].Name < rest[j].Name
+	})
+	for i := range info {
+		if info[i].Pid != 0 {
+			info[i].sample()
+		}
+	}
+	*reply = info
+	return nil
+}
+
 func (d *Daemons) Log(args []string, reply *string) error {
 	if len(args) > 0 {
 		vargs := make([]interface{}, len(args))
@@ -129,7 +310,17 @@ func (d *Daemons) Log(args []string, reply *string) error {
 }
 
 func (d *Daemons) Start(args []string, reply *struct{}) error {
-	d.start(0, args...)
+	d.start(&Manifest{Args: args})
+	return nil
+}
+
+// Ready notifies goes-daemons that the named daemon is ready.
+func (d *Daemons) Ready(name string, reply *struct{}) error {
+	u := d.unit(name)
+	if u == nil {
+		return fmt.Errorf("%s: not found", name)
+	}
+	u.notify()
 	return nil
 }
 
@@ -144,14 +335,17 @@ func (d *Daemons) Stop(pidlist []string, reply *struct{}) (err error) {
 		d.stopping = true
 		log.Print("daemon", "info", "stopping")
 		defer close(d.done)
-		// stop all in reverse order
-		pids = make([]int, len(d.pids))
-		for i, pid := range d.pids {
-			pids[len(pids)-i-1] = pid
-		}
+		pids, _ = d.stopOrder()
 		d.mutex.Unlock()
 	} else {
-		pids, err = d.pidlistToPids(pidlist)
+		var manifests []*Manifest
+		d.mutex.Lock()
+		pids, manifests, err = d.lookup(pidlist)
+		for _, m := range manifests {
+			// nor restarted by its policy
+			d.forget(m)
+		}
+		d.mutex.Unlock()
 		if err != nil {
 			return err
 		}
@@ -160,66 +354,115 @@ func (d *Daemons) Stop(pidlist []string, reply *struct{}) (err error) {
 }
 
 func (d *Daemons) Restart(pidlist []string, reply *struct{}) (err error) {
-	var pargs [][]string
+	var manifests, declared, started []*Manifest
 	var pids []int
 	d.mutex.Lock()
 	if len(pidlist) == 0 {
-		// stop all in reverse order
-		pids = make([]int, len(d.pids))
-		for i, pid := range d.pids {
-			pids[len(pids)-i-1] = pid
-		}
-		// but restart in original order
-		pargs = make([][]string, len(pids))
-		for i, pid := range d.pids {
-			p := d.cmdsByPid[pid]
-			pargs[i] = make([]string, len(p.Args))
-			copy(pargs[i], p.Args)
-		}
+		// stop all in the reverse of their order then start them
+		// again as they are at first
+		pids, started = d.stopOrder()
+		declared = d.manifests
+		manifests = append(append(manifests, declared...), started...)
 	} else {
-		pids, err = d.pidlistToPids(pidlist)
+		pids, manifests, err = d.lookup(pidlist)
 		if err != nil {
+			d.mutex.Unlock()
 			return err
 		}
-		pargs = make([][]string, len(pids))
-		for i, pid := range pids {
-			p := d.cmdsByPid[pid]
-			pargs[i] = make([]string, len(p.Args))
-			copy(pargs[i], p.Args)
-		}
 	}
 	d.mutex.Unlock()
 	if err := d.stop(pids); err != nil {
 		return err
 	}
-	for _, args := range pargs {
-		log.Print("daemon", "info", "restarting: ", args)
-		d.start(0, args...)
+	for _, m := range manifests {
+		if m == nil {
+			continue
+		}
+		log.Print("daemon", "info", "restarting: ", m.Args)
+		d.mutex.Lock()
+		d.forget(m)
+		d.mutex.Unlock()
+		if len(pidlist) > 0 {
+			d.start(m)
+		}
+	}
+	if len(pidlist) == 0 {
+		d.startAll(declared)
+		for _, m := range started {
+			d.start(m)
+		}
 	}
 	return nil
 }
 
-func (d *Daemons) pidlistToPids(pidlist []string) (pids []int, err error) {
+// stopOrder returns the pids of the daemons in the reverse of their start
+// order, that is those started by Start, latest first, and then those of
+// the manifests in the reverse of their dependency order. It also returns
+// the manifests of those started by Start. The caller must hold the mutex.
+func (d *Daemons) stopOrder() (pids []int, started []*Manifest) {
+	list, _ := order(d.manifests)
+	declared := make(map[*Manifest]bool, len(d.manifests))
+	for _, m := range d.manifests {
+		declared[m] = true
+	}
+	for i := len(d.pids) - 1; i >= 0; i-- {
+		if m := d.manifestsByPid[d.pids[i]]; !declared[m] {
+			pids = append(pids, d.pids[i])
+			started = append([]*Manifest{m}, started...)
+		}
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		for _, pid := range d.pids {
+			if d.manifestsByPid[pid] == list[i] {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	return pids, started
+}
+
+// lookup returns the pids and manifests of the daemons listed by pid or
+// name, including those that have failed or are restarting by name. The caller must hold
+// the mutex.
+func (d *Daemons) lookup(pidlist []string) (pids []int,
+	manifests []*Manifest, err error) {
 	for _, id := range pidlist {
-		pid, err := strconv.Atoi(id)
-		if err == nil {
+		if pid, err := strconv.Atoi(id); err == nil {
+			m, found := d.manifestsByPid[pid]
+			if !found {
+				return nil, nil, fmt.Errorf("%d not found", pid)
+			}
 			pids = append(pids, pid)
-		} else {
-			found := false
-			for _, pid := range d.pids {
-				if p := d.cmd(pid); p != nil {
-					if p.Args[0] == id {
-						pids = append(pids, pid)
-						found = true
-					}
-				}
+			manifests = append(manifests, m)
+			continue
+		}
+		found := false
+		for _, pid := range d.pids {
+			m := d.manifestsByPid[pid]
+			if m.String() == id || d.cmdsByPid[pid].Args[0] == id {
+				pids = append(pids, pid)
+				manifests = append(manifests, m)
+				found = true
 			}
-			if !found {
-				return nil, fmt.Errorf("%s not found", id)
+		}
+		for m, st := range d.states {
+			if (st.failed || !st.nextStart.IsZero()) &&
+				m.String() == id {
+				manifests = append(manifests, m)
+				found = true
 			}
 		}
+		if !found {
+			return nil, nil, fmt.Errorf("%s not found", id)
+		}
 	}
-	return pids, nil
+	return pids, manifests, nil
+}
+
+func (d *Daemons) unit(name string) *unit {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.units[name]
 }
 
 func (d *Daemons) cmd(pid int) *exec.Cmd {
@@ -232,6 +475,7 @@ func (d *Daemons) del(pid int) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	delete(d.cmdsByPid, pid)
+	delete(d.manifestsByPid, pid)
 	for i, entry := range d.pids {
 		if pid == entry {
 			n := copy(d.pids[i:], d.pids[i+1:])
@@ -241,47 +485,78 @@ func (d *Daemons) del(pid int) {
 	}
 }
 
+// stop each daemon in turn by running its stop command, if any, then
+// sending SIGTERM and, if it hasn't exited within its stop timeout,
+// SIGKILL.
 func (d *Daemons) stop(pids []int) error {
-	procdns := make(map[int]string)
+	const limit = 5 * time.Second
+	var err error
 	for _, pid := range pids {
-		if p := d.cmd(pid); p != nil {
-			procdns[pid] = fmt.Sprint("/proc/", pid)
-			log.Print("daemon", "info", "stopping: ", p.Args)
-			d.del(pid)
-			p.Process.Signal(syscall.SIGTERM)
-		}
-	}
-	const (
-		period = 100 * time.Millisecond
-		limit  = 5 * time.Second
-	)
-	for t := time.Duration(0); t < limit; t += period {
-		for pid, procdn := range procdns {
-			if _, err := os.Stat(procdn); os.IsNotExist(err) {
-				delete(procdns, pid)
-			}
+		d.mutex.Lock()
+		p, m := d.cmdsByPid[pid], d.manifestsByPid[pid]
+		d.mutex.Unlock()
+		if p == nil {
+			continue
 		}
-		if len(procdns) == 0 {
-			return nil
+		log.Print("daemon", "info", "stopping: ", p.Args)
+		d.del(pid)
+		d.preStop(m)
+		p.Process.Signal(syscall.SIGTERM)
+		timeout := m.stopTimeout()
+		if gone(pid, timeout) {
+			continue
+		}
+		log.Print("daemon", "err", m, ": killed after ", timeout)
+		p.Process.Signal(syscall.SIGKILL)
+		if !gone(pid, limit) && err == nil {
+			err = fmt.Errorf("%d won't die", pid)
 		}
-		time.Sleep(period)
 	}
-	for pid := range procdns {
-		syscall.Kill(pid, syscall.SIGKILL)
+	return err
+}
+
+// preStop runs the stop command of the daemon, if any, and logs its
+// output.
+func (d *Daemons) preStop(m *Manifest) {
+	if len(m.Stop.Exec) == 0 {
+		return
 	}
-	for t := time.Duration(0); t < limit; t += period {
-		for pid, procdn := range procdns {
-			if _, err := os.Stat(procdn); os.IsNotExist(err) {
-				delete(procdns, pid)
-			}
+	out := &bytes.Buffer{}
+	p := d.goes.Fork(m.Stop.Exec...)
+	p.Stdin = nil
+	p.Stdout = out
+	p.Stderr = out
+	p.Dir = "/"
+	p.Env = m.env()
+	err := p.Start()
+	if err == nil {
+		timer := time.AfterFunc(m.stopTimeout(), func() {
+			p.Process.Kill()
+		})
+		err = p.Wait()
+		timer.Stop()
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		if len(line) > 0 {
+			log.Print("daemon", "info", m, ": ", line)
+		}
+	}
+	if err != nil {
+		log.Print("daemon", "err", m, ": ", m.Stop.Exec, ": ", err)
+	}
+}
+
+// gone waits up to the limit for the process to exit.
+func gone(pid int, limit time.Duration) bool {
+	const period = 100 * time.Millisecond
+	procdn := fmt.Sprint("/proc/", pid)
+	for t := time.Duration(0); ; t += period {
+		if _, err := os.Stat(procdn); os.IsNotExist(err) {
+			return true
 		}
-		if len(procdns) == 0 {
-			return nil
+		if t >= limit {
+			return false
 		}
 		time.Sleep(period)
 	}
-	for pid, _ := range procdns {
-		return fmt.Errorf("%d won't die", pid)
-	}
-	return nil
 }
@@ -0,0 +1,391 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/platinasystems/goes/external/log"
+	"github.com/platinasystems/goes/external/redis"
+	"github.com/platinasystems/goes/internal/prog"
+)
+
+// ManifestDir has files of manifests like this, which replace those of
+// the machine with the same name.
+//
+//	# NAME then its declarations
+//	daemon vnetd
+//		args vnetd -debug
+//		after redisd
+//		requires redisd
+//		ready redis vnet.ready
+//		ready socket /run/goes/socks/vnetd
+//		ready notify
+//		ready timeout 60s
+//		restart on-failure
+//		stop exec vnet save
+//		stop timeout 10s
+//		env VNET_LOG=err
+//		memory.max 256M
+//		cpu.weight 50
+//		pids.max 64
+//
+// Each keyword other than daemon and args may be repeated. The limits are
+// those of the cgroup v2 of the daemon, e.g. /sys/fs/cgroup/goes/vnetd
+var ManifestDir = "/etc/goes/daemons.d"
+
+// DefaultReadyTimeout limits the wait for a daemon to be ready.
+var DefaultReadyTimeout = 30 * time.Second
+
+// DefaultStopTimeout limits the wait for a daemon to exit after SIGTERM
+// before it's killed.
+var DefaultStopTimeout = 5 * time.Second
+
+// DaemonEnv names the daemon in its environment so that it may notify its
+// readiness with `daemon ready`.
+const DaemonEnv = "GOES_DAEMON"
+
+// A Manifest declares a daemon run by goes-daemons.
+type Manifest struct {
+	// Name of the daemon, by default Args[0]
+	Name string
+	// Args of the goes command
+	Args []string
+	// After names the daemons that, if declared, must be ready before
+	// this one is started.
+	After []string
+	// Requires names the daemons that must be ready before this one is
+	// started; without them, it isn't.
+	Requires []string
+	Ready    Readiness
+	// Restart is one of the policies, never, on-failure, or always,
+	// or by default, DefaultRestart.
+	Restart string
+	Stop    Stopping
+	// Env has NAME=VALUE added to the environment of the daemon.
+	Env []string
+	// Limits has the values of the cgroup files, memory.max,
+	// cpu.weight, and pids.max, of the daemon.
+	Limits map[string]string
+}
+
+// Readiness is the condition that a daemon is ready for its dependents.
+// A daemon without a condition is ready once started.
+type Readiness struct {
+	// Redis is the field of the default hash that the daemon sets
+	// "true" when ready, e.g. "redis.ready"
+	Redis string
+	// Socket is the unix socket file that the daemon creates when
+	// ready.
+	Socket string
+	// Notify is whether the daemon runs `daemon ready` when ready.
+	Notify bool
+	// Timeout, if not DefaultReadyTimeout
+	Timeout time.Duration
+}
+
+// Stopping is how a daemon is stopped by admin or goes-daemons exit.
+type Stopping struct {
+	// Exec is the goes command, if any, that's run before the daemon is
+	// sent SIGTERM, e.g. to save its state.
+	Exec []string
+	// Timeout, if not DefaultStopTimeout, limits the stop command and
+	// then the wait for the daemon to exit before it's killed.
+	Timeout time.Duration
+}
+
+var restartPolicies = []string{"", "never", "on-failure", "always"}
+
+// A unit is the readiness of a declared daemon, which is done once it's
+// ready or has failed to become so.
+type unit struct {
+	done       chan struct{}
+	ok         bool
+	once       sync.Once
+	notified   chan struct{}
+	notifyOnce sync.Once
+}
+
+func newUnit() *unit {
+	return &unit{
+		done:     make(chan struct{}),
+		notified: make(chan struct{}),
+	}
+}
+
+func (u *unit) set(ok bool) {
+	u.once.Do(func() {
+		u.ok = ok
+		close(u.done)
+	})
+}
+
+func (u *unit) isDone() bool {
+	select {
+	case <-u.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (u *unit) notify() {
+	u.notifyOnce.Do(func() { close(u.notified) })
+}
+
+// await the readiness of the started daemon.
+func (u *unit) await(m *Manifest) {
+	timeout := m.Ready.Timeout
+	if timeout == 0 {
+		timeout = DefaultReadyTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := m.Ready.wait(ctx, u.notified)
+	if err != nil {
+		log.Print("daemon", "err", m, ": not ready: ", err)
+	} else {
+		log.Print("daemon", "info", m, ": ready")
+	}
+	u.set(err == nil)
+}
+
+func (r Readiness) wait(ctx context.Context, notified <-chan struct{}) error {
+	if len(r.Redis) > 0 {
+		deadline, _ := ctx.Deadline()
+		err := redis.HwaitContext(ctx, redis.DefaultHash, r.Redis,
+			"true", time.Until(deadline))
+		if err != nil {
+			return err
+		}
+	}
+	if len(r.Socket) > 0 {
+		tick := time.NewTicker(100 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			fi, err := os.Stat(r.Socket)
+			if err == nil && fi.Mode()&os.ModeSocket != 0 {
+				break
+			}
+			select {
+			case <-tick.C:
+			case <-ctx.Done():
+				return fmt.Errorf("%s: %v", r.Socket, ctx.Err())
+			}
+		}
+	}
+	if r.Notify {
+		select {
+		case <-notified:
+		case <-ctx.Done():
+			return fmt.Errorf("notify: %v", ctx.Err())
+		}
+	}
+	return nil
+}
+
+func (m *Manifest) stopTimeout() time.Duration {
+	if m.Stop.Timeout > 0 {
+		return m.Stop.Timeout
+	}
+	return DefaultStopTimeout
+}
+
+// env returns the environment of the daemon and its stop command.
+func (m *Manifest) env() []string {
+	env := append(prog.DaemonEnv(), m.Env...)
+	return append(env, DaemonEnv+"="+m.String())
+}
+
+func (m *Manifest) String() string {
+	if len(m.Name) > 0 {
+		return m.Name
+	}
+	return m.Args[0]
+}
+
+// LoadManifests reads the .conf files of the named directory in order.
+func LoadManifests(dir string) ([]*Manifest, error) {
+	fns, err := filepath.Glob(filepath.Join(dir, "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	var manifests []*Manifest
+	for _, fn := range fns {
+		f, err := os.Open(fn)
+		if err != nil {
+			return nil, err
+		}
+		ms, err := ReadManifests(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s:%v", fn, err)
+		}
+		manifests = append(manifests, ms...)
+	}
+	return manifests, nil
+}
+
+// ReadManifests parses the format described with ManifestDir.
+func ReadManifests(r io.Reader) ([]*Manifest, error) {
+	var manifests []*Manifest
+	var m *Manifest
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := scanner.Text()
+		if i := strings.IndexByte(s, '#'); i >= 0 {
+			s = s[:i]
+		}
+		fields := strings.Fields(s)
+		if len(fields) == 0 {
+			continue
+		}
+		keyword := fields[0]
+		if keyword == "daemon" && len(fields) == 2 {
+			m = &Manifest{Name: fields[1]}
+			manifests = append(manifests, m)
+			continue
+		}
+		if m == nil {
+			return nil, fmt.Errorf("%d: %s: outside daemon",
+				line, keyword)
+		}
+		if err := m.parse(fields); err != nil {
+			return nil, fmt.Errorf("%d: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, m := range manifests {
+		if len(m.Args) == 0 {
+			m.Args = []string{m.Name}
+		}
+	}
+	return manifests, nil
+}
+
+func (m *Manifest) parse(fields []string) error {
+	switch keyword, args := fields[0], fields[1:]; {
+	case keyword == "args" && len(args) > 0:
+		m.Args = args
+	case keyword == "after" && len(args) > 0:
+		m.After = append(m.After, args...)
+	case keyword == "requires" && len(args) > 0:
+		m.Requires = append(m.Requires, args...)
+	case keyword == "ready" && len(args) == 2 && args[0] == "redis":
+		m.Ready.Redis = args[1]
+	case keyword == "ready" && len(args) == 2 && args[0] == "socket":
+		m.Ready.Socket = args[1]
+	case keyword == "ready" && len(args) == 1 && args[0] == "notify":
+		m.Ready.Notify = true
+	case keyword == "ready" && len(args) == 2 && args[0] == "timeout":
+		t, err := time.ParseDuration(args[1])
+		if err != nil || t <= 0 {
+			return fmt.Errorf("%s: invalid timeout", args[1])
+		}
+		m.Ready.Timeout = t
+	case keyword == "restart" && len(args) == 1:
+		for _, policy := range restartPolicies[1:] {
+			if args[0] == policy {
+				m.Restart = policy
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: invalid restart", args[0])
+	case keyword == "stop" && len(args) > 1 && args[0] == "exec":
+		m.Stop.Exec = args[1:]
+	case keyword == "stop" && len(args) == 2 && args[0] == "timeout":
+		t, err := time.ParseDuration(args[1])
+		if err != nil || t <= 0 {
+			return fmt.Errorf("%s: invalid timeout", args[1])
+		}
+		m.Stop.Timeout = t
+	case keyword == "env" && len(args) > 0:
+		for _, kv := range args {
+			if !strings.Contains(kv, "=") {
+				return fmt.Errorf("%s: invalid env", kv)
+			}
+		}
+		m.Env = append(m.Env, args...)
+	case isLimit(keyword) && len(args) == 1:
+		if !validLimit(keyword, args[0]) {
+			return fmt.Errorf("%s: invalid %s", args[0], keyword)
+		}
+		if m.Limits == nil {
+			m.Limits = make(map[string]string)
+		}
+		m.Limits[keyword] = args[0]
+	default:
+		return fmt.Errorf("%q: invalid", strings.Join(fields, " "))
+	}
+	return nil
+}
+
+// order returns the manifests with each after its declared dependencies
+// but otherwise in the given order, with an error for each that's in a
+// dependency cycle or requires an undeclared daemon.
+func order(manifests []*Manifest) (list []*Manifest, errs []error) {
+	byName := make(map[string]*Manifest)
+	for _, m := range manifests {
+		byName[m.String()] = m
+	}
+	const (
+		visiting = iota + 1
+		ordered
+		failed
+	)
+	state := make(map[*Manifest]int)
+	var visit func(m *Manifest, path []string) bool
+	visit = func(m *Manifest, path []string) bool {
+		switch state[m] {
+		case ordered:
+			return true
+		case failed:
+			return false
+		case visiting:
+			errs = append(errs, fmt.Errorf("%s: cycle",
+				strings.Join(append(path, m.String()), " -> ")))
+			return false
+		}
+		state[m] = visiting
+		path = append(path, m.String())
+		ok := true
+		for _, name := range m.Requires {
+			dep, found := byName[name]
+			if !found {
+				errs = append(errs, fmt.Errorf(
+					"%s: requires %s: not declared", m, name))
+				ok = false
+			} else if !visit(dep, path) {
+				ok = false
+			}
+		}
+		for _, name := range m.After {
+			if dep, found := byName[name]; found && !visit(dep, path) {
+				ok = false
+			}
+		}
+		if ok {
+			state[m] = ordered
+			list = append(list, m)
+		} else {
+			state[m] = failed
+		}
+		return ok
+	}
+	for _, m := range manifests {
+		visit(m, nil)
+	}
+	return
+}
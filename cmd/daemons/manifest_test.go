@@ -0,0 +1,150 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testManifests = `
+# comment
+daemon vnetd
+	args vnetd -debug
+	requires redisd
+	ready socket /run/goes/socks/vnetd
+	ready timeout 1m
+	restart on-failure
+	stop exec vnet save
+	stop timeout 10s
+	env A=1 B=2
+	memory.max 256M
+	pids.max 64
+daemon redisd
+	ready redis redis.ready
+daemon uptimed
+	after vnetd nosuchd
+`
+
+func TestReadManifests(t *testing.T) {
+	ms, err := ReadManifests(strings.NewReader(testManifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 3 {
+		t.Fatal("wrong:", len(ms))
+	}
+	if !reflect.DeepEqual(ms[0], &Manifest{
+		Name:     "vnetd",
+		Args:     []string{"vnetd", "-debug"},
+		Requires: []string{"redisd"},
+		Ready: Readiness{
+			Socket:  "/run/goes/socks/vnetd",
+			Timeout: time.Minute,
+		},
+		Restart: "on-failure",
+		Stop: Stopping{
+			Exec:    []string{"vnet", "save"},
+			Timeout: 10 * time.Second,
+		},
+		Env: []string{"A=1", "B=2"},
+		Limits: map[string]string{
+			"memory.max": "256M",
+			"pids.max":   "64",
+		},
+	}) {
+		t.Error("wrong:", ms[0])
+	}
+	if !reflect.DeepEqual(ms[1].Args, []string{"redisd"}) {
+		t.Error("wrong:", ms[1].Args)
+	}
+	for _, s := range []string{
+		"args redisd",
+		"daemon redisd\n\trestart sometimes",
+		"daemon redisd\n\tready whenever",
+		"daemon redisd\n\tenv A",
+		"daemon redisd\n\tmemory.max 1X",
+		"daemon redisd\n\tcpu.weight 0",
+		"daemon redisd\n\tpids.max -1",
+		"daemon redisd\n\tstop timeout never",
+	} {
+		if _, err := ReadManifests(strings.NewReader(s)); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	ms, _ := ReadManifests(strings.NewReader(testManifests))
+	ms = append(ms,
+		&Manifest{Name: "a", Args: []string{"a"}, After: []string{"b"}},
+		&Manifest{Name: "b", Args: []string{"b"}, After: []string{"a"}},
+		&Manifest{Name: "c", Args: []string{"c"},
+			Requires: []string{"a"}},
+		&Manifest{Name: "d", Args: []string{"d"},
+			Requires: []string{"nosuchd"}})
+	list, errs := order(ms)
+	var names []string
+	for _, m := range list {
+		names = append(names, m.String())
+	}
+	if !reflect.DeepEqual(names, []string{"redisd", "vnetd", "uptimed"}) {
+		t.Error("wrong:", names)
+	}
+	if len(errs) != 2 {
+		t.Error("wrong:", errs)
+	}
+}
+
+func TestStopOrder(t *testing.T) {
+	ms, _ := ReadManifests(strings.NewReader(testManifests))
+	started := &Manifest{Args: []string{"started"}}
+	d := Daemons{
+		manifests:      ms,
+		manifestsByPid: make(map[int]*Manifest),
+	}
+	// redisd, started, then uptimed and vnetd as restarted
+	for i, m := range []*Manifest{ms[1], started, ms[2], ms[0]} {
+		pid := 100 + i
+		d.pids = append(d.pids, pid)
+		d.manifestsByPid[pid] = m
+	}
+	pids, adhoc := d.stopOrder()
+	if !reflect.DeepEqual(pids, []int{101, 102, 103, 100}) {
+		t.Error("wrong:", pids)
+	}
+	if len(adhoc) != 1 || adhoc[0] != started {
+		t.Error("wrong:", adhoc)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	running := &Manifest{Args: []string{"running"}}
+	d := Daemons{
+		pids:           []int{100},
+		cmdsByPid:      map[int]*exec.Cmd{100: {Args: running.Args}},
+		manifestsByPid: map[int]*Manifest{100: running},
+		states: map[*Manifest]*state{
+			{Args: []string{"b"}}: {failed: true},
+			{Args: []string{"c"}}: {nextStart: time.Now()},
+			{Args: []string{"a"}}: {failed: true},
+		},
+	}
+	var info []Info
+	d.Status(struct{}{}, &info)
+	var names []string
+	for _, i := range info {
+		names = append(names, i.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"running", "a", "b", "c"}) {
+		t.Error("wrong:", names)
+	}
+	if len(d.states) != 3 {
+		t.Error("wrong:", len(d.states), "states")
+	}
+}
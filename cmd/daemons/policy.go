@@ -0,0 +1,139 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/platinasystems/goes/external/log"
+	"github.com/platinasystems/goes/external/redis"
+)
+
+// A daemon is restarted after a backoff, beginning with RestartBackoff and
+// doubling with each restart within the CrashLoopWindow, up to
+// RestartBackoffMax. Each delay is jittered by up to half. A daemon that
+// exits after CrashLoopRestarts within the window is marked failed rather
+// than restarted.
+var (
+	RestartBackoff    = time.Second
+	RestartBackoffMax = 30 * time.Second
+	CrashLoopRestarts = 5
+	CrashLoopWindow   = 60 * time.Second
+)
+
+// Hash is the redis hash of each daemon's state, e.g. "vnetd.state".
+const Hash = "daemons"
+
+// A state is that of a daemon across its restarts.
+type state struct {
+	restarts    int
+	lastExit    string
+	lastRestart time.Time
+	failed      bool
+	// nextStart is that of a daemon waiting to restart
+	nextStart time.Time
+	// recent restarts within the CrashLoopWindow
+	recent []time.Time
+}
+
+func (m *Manifest) restart() string {
+	if len(m.Restart) > 0 {
+		return m.Restart
+	}
+	return DefaultRestart
+}
+
+// state returns that of the daemon, which is kept until it's removed, i.e.
+// stopped or restarted by admin or exited without restart.
+func (d *Daemons) state(m *Manifest) *state {
+	st, found := d.states[m]
+	if !found {
+		st = new(state)
+		d.states[m] = st
+	}
+	return st
+}
+
+// forget the state of the removed daemon, including that published of its
+// failure. The caller must hold the mutex.
+func (d *Daemons) forget(m *Manifest) {
+	if st, found := d.states[m]; found && st.failed {
+		redis.Hdel(Hash, m.String()+".state")
+	}
+	delete(d.states, m)
+}
+
+// exited records the exit of the daemon and, as its policy allows,
+// restarts it after a backoff.
+func (d *Daemons) exited(m *Manifest, ps *os.ProcessState, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	switch policy := m.restart(); {
+	case policy == "never", policy == "on-failure" && err == nil:
+		d.forget(m)
+		return
+	}
+	st := d.state(m)
+	st.lastExit = exitOf(ps)
+	now := time.Now()
+	recent := st.recent[:0]
+	for _, t := range st.recent {
+		if now.Sub(t) < CrashLoopWindow {
+			recent = append(recent, t)
+		}
+	}
+	st.recent = recent
+	if len(st.recent) >= CrashLoopRestarts {
+		st.failed = true
+		log.Print("daemon", "err", m, ": failed after ",
+			len(st.recent), " restarts in ", CrashLoopWindow)
+		if _, err := redis.Hset(Hash, m.String()+".state",
+			"failed"); err != nil {
+			log.Print("daemon", "err", m, ": ", err)
+		}
+		return
+	}
+	st.recent = append(st.recent, now)
+	delay := RestartBackoff << uint(len(st.recent)-1)
+	if delay <= 0 || delay > RestartBackoffMax {
+		delay = RestartBackoffMax
+	}
+	delay -= time.Duration(rand.Int63n(int64(delay/2) + 1))
+	log.Print("daemon", "info", m, ": restart in ", delay.Round(time.Millisecond))
+	st.nextStart = now.Add(delay)
+	go func() {
+		select {
+		case <-time.After(delay):
+		case <-d.done:
+			return
+		}
+		d.mutex.Lock()
+		if d.states[m] != st || st.nextStart.IsZero() {
+			// since restarted or stopped by admin
+			d.mutex.Unlock()
+			return
+		}
+		st.nextStart = time.Time{}
+		st.restarts++
+		st.lastRestart = time.Now()
+		d.mutex.Unlock()
+		d.start(m)
+	}()
+}
+
+// exitOf returns the exit status or signal of the process.
+func exitOf(ps *os.ProcessState) string {
+	if ps == nil {
+		return ""
+	}
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return fmt.Sprint("signal ", ws.Signal())
+	}
+	return fmt.Sprint("status ", ps.ExitCode())
+}
@@ -6,4 +6,5 @@
 
 package daemons
 
-const RestartLimit = 1
+// DefaultRestart is the policy of daemons without one.
+const DefaultRestart = "always"
@@ -14,6 +14,7 @@ import (
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/external/atsock"
+	"github.com/platinasystems/goes/external/log"
 	"github.com/platinasystems/goes/lang"
 )
 
@@ -24,7 +25,12 @@ type Server struct {
 	//	redis.Hwait(redis.DefaultHash, "redis.ready", "true", TIMEOUT)
 	// or
 	//	redis.IsReady()
+	// unless declared with Manifests instead.
 	Init [][]string
+	// Manifests declare daemons that run from start after those of Init.
+	// These, in turn, are replaced by those of ManifestDir with the same
+	// name or otherwise followed by them.
+	Manifests []*Manifest
 	Daemons
 }
 
@@ -59,9 +65,8 @@ func (c *Server) Main(args ...string) error {
 	}
 	defer c.rpc.Close()
 
-	for _, dargs := range c.Init {
-		c.Daemons.start(0, dargs...)
-	}
+	c.Daemons.startAll(c.manifests())
+	go c.Daemons.publish()
 
 	rpc.Register(&c.Daemons)
 
@@ -77,3 +82,27 @@ func (c *Server) Main(args ...string) error {
 		}
 	}
 }
+
+func (c *Server) manifests() []*Manifest {
+	manifests := make([]*Manifest, 0, len(c.Init)+len(c.Manifests))
+	for _, args := range c.Init {
+		manifests = append(manifests, &Manifest{Args: args})
+	}
+	manifests = append(manifests, c.Manifests...)
+	conf, err := LoadManifests(ManifestDir)
+	if err != nil {
+		log.Print("daemon", "err", err)
+	}
+	for _, m := range conf {
+		i := 0
+		for i < len(manifests) && manifests[i].String() != m.String() {
+			i++
+		}
+		if i < len(manifests) {
+			manifests[i] = m
+		} else {
+			manifests = append(manifests, m)
+		}
+	}
+	return manifests
+}
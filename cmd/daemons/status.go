@@ -0,0 +1,162 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package daemons
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/platinasystems/goes/external/redis"
+	"github.com/platinasystems/goes/internal/cgroup"
+	"github.com/platinasystems/goes/internal/proc"
+)
+
+// PublishInterval is that of the Info of each daemon in the redis Hash,
+// e.g. "vnetd.rss".
+var PublishInterval = 10 * time.Second
+
+// sample the resource usage of a running daemon from /proc.
+func (i *Info) sample() {
+	var stat proc.Stat
+	var statm proc.Statm
+	dn := fmt.Sprint("/proc/", i.Pid)
+	if proc.Load(&stat).FromFile(dn+"/stat") == nil {
+		i.Uptime = time.Since(stat.StartTime).Round(time.Second)
+		i.CPU = stat.Utime + stat.Stime
+		i.Threads = int(stat.NumThreads)
+	}
+	if proc.Load(&statm).FromFile(dn+"/statm") == nil {
+		i.RSS = statm.Resident * uint64(os.Getpagesize())
+	}
+	if fds, err := ioutil.ReadDir(dn + "/fd"); err == nil {
+		i.FDs = len(fds)
+	}
+	if cg, found := cgroup.Of(i.Pid); found {
+		st := cg.Stat()
+		i.Cgroup = &st
+	}
+}
+
+// fields are those of the Info published in the redis Hash.
+func (i *Info) fields() [][2]string {
+	var cg cgroup.Stat
+	if i.Cgroup != nil {
+		cg = *i.Cgroup
+	}
+	var next string
+	if i.NextStart != nil {
+		next = i.NextStart.Format(time.RFC3339)
+	}
+	return [][2]string{
+		{"pid", strconv.Itoa(i.Pid)},
+		{"state", i.State},
+		{"next_start", next},
+		{"uptime", i.Uptime.String()},
+		{"restarts", strconv.Itoa(i.Restarts)},
+		{"last_exit", i.LastExit},
+		{"rss", strconv.FormatUint(i.RSS, 10)},
+		{"cpu", i.CPU.String()},
+		{"fds", strconv.Itoa(i.FDs)},
+		{"threads", strconv.Itoa(i.Threads)},
+		{"cgroup.memory", strconv.FormatUint(cg.Memory, 10)},
+		{"cgroup.cpu", cg.CPU.String()},
+		{"cgroup.pids", strconv.FormatUint(cg.Pids, 10)},
+		{"cgroup.oom_kills", strconv.FormatUint(cg.OOMKills, 10)},
+	}
+}
+
+// publish the Info of each daemon to redis until stopped, removing the
+// fields of those that are no longer supervised.
+func (d *Daemons) publish() {
+	published := make(map[string]bool)
+	t := time.NewTicker(PublishInterval)
+	defer t.Stop()
+ticks:
+	for {
+		select {
+		case <-t.C:
+		case <-d.done:
+			return
+		}
+		var info []Info
+		d.Status(struct{}{}, &info)
+		current := make(map[string]bool)
+		for _, i := range info {
+			current[i.Name] = true
+			for _, kv := range i.fields() {
+				_, err := redis.Hset(Hash, i.Name+"."+kv[0], kv[1])
+				if err != nil {
+					// redisd isn't ready
+					continue ticks
+				}
+			}
+		}
+		for name := range published {
+			if !current[name] {
+				for _, kv := range (&Info{}).fields() {
+					redis.Hdel(Hash, name+"."+kv[0])
+				}
+			}
+		}
+		published = current
+	}
+}
+
+// Fprint a table of the daemons.
+func Fprint(w io.Writer, info []Info) {
+	const format = "%-12s %7s %-10s %9s %8s %8s %9s %5s %7s %15s %9s %s\n"
+	fmt.Fprintf(w, format, "NAME", "PID", "STATE", "UPTIME", "RESTARTS",
+		"RSS", "CPU", "FDS", "THREADS", "CG-MEMORY", "CG-PIDS",
+		"LAST-EXIT")
+	for _, i := range info {
+		pid := "-"
+		if i.Pid != 0 {
+			pid = strconv.Itoa(i.Pid)
+		}
+		memory, pids := "-", "-"
+		if cg := i.Cgroup; cg != nil {
+			memory = size(cg.Memory) + "/" + limit(cg.MemoryMax, size)
+			pids = strconv.FormatUint(cg.Pids, 10) + "/" +
+				limit(cg.PidsMax, func(n uint64) string {
+					return strconv.FormatUint(n, 10)
+				})
+		}
+		// that of a restarting daemon is until its next start
+		uptime := i.Uptime.String()
+		if i.NextStart != nil {
+			uptime = "in " + time.Until(*i.NextStart).
+				Round(time.Second).String()
+		}
+		fmt.Fprintf(w, format, i.Name, pid, i.State, uptime,
+			strconv.Itoa(i.Restarts), size(i.RSS),
+			i.CPU.Round(10*time.Millisecond), strconv.Itoa(i.FDs),
+			strconv.Itoa(i.Threads), memory, pids, i.LastExit)
+	}
+}
+
+// limit formats the max of a cgroup, which is zero if unlimited.
+func limit(max uint64, format func(uint64) string) string {
+	if max == 0 {
+		return "max"
+	}
+	return format(max)
+}
+
+func size(b uint64) string {
+	const units = "KMGT"
+	if b < 1024 {
+		return strconv.FormatUint(b, 10)
+	}
+	f := float64(b) / 1024
+	i := 0
+	for ; f >= 1024 && i < len(units)-1; i++ {
+		f /= 1024
+	}
+	return fmt.Sprintf("%.1f%c", f, units[i])
+}
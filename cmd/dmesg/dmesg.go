@@ -10,9 +10,8 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/platinasystems/goes/external/flags"
 	"github.com/platinasystems/goes/external/log"
-	"github.com/platinasystems/goes/external/parms"
+	"github.com/platinasystems/goes/external/options"
 	"github.com/platinasystems/goes/lang"
 )
 
@@ -34,7 +33,28 @@ type Command struct{}
 
 func (Command) String() string { return "dmesg" }
 
-func (Command) Usage() string { return "dmesg [OPTION]..." }
+var opts = options.Options{
+	{Name: "-C", Help: "Clear the ring buffer."},
+	{Name: "-c", Help: "Clear the ring buffer after first printing its contents."},
+	{Name: "-D", Help: "Disable the printing of messages to the console."},
+	{Name: "-d", Help: "Display the delta time between messages."},
+	{Name: "-E", Help: "Enable printing messages to the console."},
+	{Name: "-F", Type: options.File, Arg: "FILE", Default: "/dev/kmsg",
+		Help: "Read the messages from FILE."},
+	{Name: "-H", Help: "Enable human-readable output."},
+	{Name: "-k", Help: "Print kernel messages."},
+	{Name: "-n", Type: options.Choice("emerg", "alert", "crit", "err",
+		"warn", "note", "info", "debug"), Arg: "LEVEL",
+		Help: "Set console to the given named log level."},
+	{Name: "-r", Help: "Print the raw message, i.e. do not strip the priority prefix."},
+	{Name: "-T", Help: "Print human-readable timestamps."},
+	{Name: "-t", Help: "Do not print timestamps."},
+	{Name: "-u", Help: "Print userspace messages."},
+	{Name: "-x", Help: "Decode facility and level (priority) numbers."},
+	{Name: "-z", Help: "Reprint entire ring buffer."},
+}
+
+func (Command) Usage() string { return opts.Usage("dmesg") }
 
 func (Command) Apropos() lang.Alt {
 	return lang.Alt{
@@ -49,27 +69,13 @@ DESCRIPTION
 	The default action is to print new kernel ring buffer messages since
 	the last command invocation.
 
-OPTIONS
-	-C	Clear the ring buffer.
-	-c	Clear the ring buffer after first printing its contents.
-	-D	Disable the printing of messages to the console.
-	-d	Display the delta time between messages.
-	-E	Enable printing messages to the console.
-	-F	Read the messages from the given file instead of /dev/kmsg.
-	-H	Enable human-readable output.
-	-k	Print kernel messages.
-	-n level
-		Set console to the given numbered or named log level.
-	-r	Print the raw message, i.e. do not strip the priority prefix.
-	-T	Print human-readable timestamps.
-	-t	Do not print timestamps.
-	-u	Print userspace messages.
-	-w	Wait for new messages.
-	-x	Decode facility and level (priority) numbers.
-	-z	Reprint entire ring buffer.`,
+` + opts.Man() + `
+	-json	Print the entire ring buffer as JSON, with -F, -k and -u.`,
 	}
 }
 
+func (Command) Options() options.Options { return opts }
+
 func (Command) Main(args ...string) error {
 	const (
 		nl = "\n"
@@ -85,18 +91,15 @@ func (Command) Main(args ...string) error {
 	var event syscall.EpollEvent
 	var events [MaxEpollEvents]syscall.EpollEvent
 
-	flag, args := flags.New(args, "-C", "-c", "-D", "-d", "-E", "-H",
-		"-k", "-r", "-T", "-t", "-u", "-x", "-z")
-	parm, args := parms.New(args, "-F", "-n")
-	if len(parm.ByName["-F"]) == 0 {
-		parm.ByName["-F"] = "/dev/kmsg"
+	opt, args, err := opts.Parse(args)
+	if err != nil {
+		return err
 	}
-
 	if len(args) > 0 {
-		_ = fmt.Errorf("%v: unexpected", args)
+		return fmt.Errorf("%v: unexpected", args)
 	}
 
-	f, err := os.Open(parm.ByName["-F"])
+	f, err := os.Open(opt.String("-F"))
 	if err != nil {
 		return err
 	}
@@ -115,28 +118,25 @@ func (Command) Main(args ...string) error {
 	buf := make([]byte, 4096)
 	defer func() { buf = buf[:0] }()
 
-	if flag.ByName["-C"] {
+	if opt.Bool("-C") {
 		_, err = syscall.Klogctl(SYSLOG_ACTION_CLEAR, buf)
 		return err
 	}
-	if flag.ByName["-D"] {
+	if opt.Bool("-D") {
 		_, err = syscall.Klogctl(SYSLOG_ACTION_CONSOLE_OFF, buf)
 		return err
 	}
-	if flag.ByName["-E"] {
+	if opt.Bool("-E") {
 		_, err = syscall.Klogctl(SYSLOG_ACTION_CONSOLE_ON, buf)
 		return err
 	}
-	if s := parm.ByName["-n"]; len(s) > 0 {
-		pri, found := log.PriorityByName[s]
-		if !found {
-			_ = fmt.Errorf("%s: unknown", s)
-		}
+	if s := opt.String("-n"); len(s) > 0 {
+		pri := log.PriorityByName[s]
 		_, err = syscall.Klogctl(SYSLOG_ACTION_CONSOLE_LEVEL,
 			buf[:pri])
 		return err
 	}
-	if flag.ByName["-z"] {
+	if opt.Bool("-z") {
 		last.Seq = 0
 	}
 
@@ -166,8 +166,8 @@ func (Command) Main(args ...string) error {
 				kmsg.Parse(buf[:n])
 
 				if kmsg.Stamp == log.Stamp(0) ||
-					(flag.ByName["-k"] && !kmsg.IsKern()) ||
-					(flag.ByName["-u"] && kmsg.IsKern()) {
+					(opt.Bool("-k") && !kmsg.IsKern()) ||
+					(opt.Bool("-u") && kmsg.IsKern()) {
 					continue
 				}
 
@@ -184,63 +184,63 @@ func (Command) Main(args ...string) error {
 						log.LogFacilityByValue[fac]+":",
 						log.LogPriorityByValue[pri]+":")
 					switch {
-					case flag.ByName["-H"] &&
-						flag.ByName["-d"] &&
-						flag.ByName["-x"]:
+					case opt.Bool("-H") &&
+						opt.Bool("-d") &&
+						opt.Bool("-x"):
 						fmt.Print(xs,
 							lb, t.H(), sp, lt, delta, gt, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-H"] && flag.ByName["-d"]:
+					case opt.Bool("-H") && opt.Bool("-d"):
 						fmt.Print(lb, t.H(), sp, lt, delta, gt, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-H"] && flag.ByName["-x"]:
+					case opt.Bool("-H") && opt.Bool("-x"):
 						fmt.Print(xs, sp,
 							lb, t.H(), rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-H"]:
+					case opt.Bool("-H"):
 						fmt.Print(lb, t.H(), rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-T"] &&
-						flag.ByName["-d"] &&
-						flag.ByName["-x"]:
+					case opt.Bool("-T") &&
+						opt.Bool("-d") &&
+						opt.Bool("-x"):
 						fmt.Print(xs,
 							lb, t.T(), sp, lt, delta, gt, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-T"] && flag.ByName["-d"]:
+					case opt.Bool("-T") && opt.Bool("-d"):
 						fmt.Print(lb, t.T(), sp, lt, delta, gt, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-T"] && flag.ByName["-x"]:
+					case opt.Bool("-T") && opt.Bool("-x"):
 						fmt.Print(xs, sp,
 							lb, t.T(), rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-T"]:
+					case opt.Bool("-T"):
 						fmt.Print(lb, t.T(), rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-t"] &&
-						flag.ByName["-d"] &&
-						flag.ByName["-x"]:
+					case opt.Bool("-t") &&
+						opt.Bool("-d") &&
+						opt.Bool("-x"):
 						fmt.Print(xs, sp,
 							lb, lt, delta, gt, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-t"] && flag.ByName["-d"]:
+					case opt.Bool("-t") && opt.Bool("-d"):
 						fmt.Print(lb, lt, delta, gt, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-t"] && flag.ByName["-x"]:
+					case opt.Bool("-t") && opt.Bool("-x"):
 						fmt.Print(xs, sp, kmsg.Msg, nl)
-					case flag.ByName["-t"]:
+					case opt.Bool("-t"):
 						fmt.Print(kmsg.Msg, nl)
-					case flag.ByName["-r"]:
+					case opt.Bool("-r"):
 						fmt.Print(lt, kmsg.Pri, gt,
 							lb, kmsg.Stamp, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-d"] && flag.ByName["-x"]:
+					case opt.Bool("-d") && opt.Bool("-x"):
 						fmt.Print(xs,
 							lb, kmsg.Stamp, sp, lt, delta, gt, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-d"]:
+					case opt.Bool("-d"):
 						fmt.Print(lb, kmsg.Stamp, sp, lt, delta, gt, rb,
 							sp, kmsg.Msg, nl)
-					case flag.ByName["-x"]:
+					case opt.Bool("-x"):
 						fmt.Print(xs, sp,
 							lb, kmsg.Stamp, rb,
 							sp, kmsg.Msg, nl)
@@ -255,7 +255,7 @@ func (Command) Main(args ...string) error {
 		}
 	}
 
-	if flag.ByName["-c"] {
+	if opt.Bool("-c") {
 		_, err = syscall.Klogctl(SYSLOG_ACTION_CLEAR, buf)
 		if err != nil {
 			return err
@@ -273,3 +273,65 @@ func (t timeT) H() string {
 func (t timeT) T() string {
 	return time.Time(t).Format("Mon " + time.Stamp + " 2006")
 }
+
+// A Message is the JSON object of a kernel ring buffer message.
+type Message struct {
+	Seq      uint64    `json:"seq"`
+	Facility string    `json:"facility"`
+	Priority string    `json:"priority"`
+	Stamp    string    `json:"stamp"`
+	Time     time.Time `json:"time"`
+	Msg      string    `json:"msg"`
+}
+
+// JSON returns the entire ring buffer, or just its kernel or userspace
+// messages with -k or -u.
+func (Command) JSON(args ...string) (interface{}, error) {
+	opt, args, err := opts.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%v: unexpected", args)
+	}
+	f, err := os.Open(opt.String("-F"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+	if err = syscall.SetNonblock(fd, true); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var si syscall.Sysinfo_t
+	if err = syscall.Sysinfo(&si); err != nil {
+		return nil, err
+	}
+	msgs := []Message{}
+	buf := make([]byte, 4096)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if err != nil || n <= 0 {
+			break
+		}
+		var kmsg log.Kmsg
+		kmsg.Parse(buf[:n])
+		if kmsg.Stamp == log.Stamp(0) ||
+			(opt.Bool("-k") && !kmsg.IsKern()) ||
+			(opt.Bool("-u") && kmsg.IsKern()) {
+			continue
+		}
+		msgs = append(msgs, Message{
+			Seq: uint64(kmsg.Seq),
+			Facility: log.LogFacilityByValue[kmsg.Pri&
+				log.FacilityMask],
+			Priority: log.LogPriorityByValue[kmsg.Pri&
+				log.PriorityMask],
+			Stamp: kmsg.Stamp.String(),
+			Time:  kmsg.Stamp.Time(now, int64(si.Uptime)),
+			Msg:   kmsg.Msg,
+		})
+	}
+	return msgs, nil
+}
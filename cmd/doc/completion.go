@@ -0,0 +1,63 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// bash returns a script that completes the command names of each tree and
+// otherwise runs "goes complete".
+func bash(pages []*page) string {
+	prog := pages[0].path[0]
+	fn := "_" + strings.Replace(prog, "-", "_", -1)
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# generated by %s doc\n\n", prog)
+	fmt.Fprintf(buf, "%s ()\n{\n", fn)
+	fmt.Fprint(buf, "\tlocal cur=${COMP_WORDS[COMP_CWORD]}\n")
+	fmt.Fprint(buf, "\tcase \"${COMP_WORDS[*]:1:COMP_CWORD-1}\" in\n")
+	for _, p := range pages {
+		if len(p.subs) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "\t%q)\n", strings.Join(p.path[1:], " "))
+		fmt.Fprintf(buf, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n",
+			strings.Join(p.subs, " "))
+		fmt.Fprint(buf, "\t\t;;\n")
+	}
+	fmt.Fprint(buf, "\t*)\n")
+	fmt.Fprintf(buf, "\t\tCOMPREPLY=($(%s complete \"${COMP_WORDS[@]:1:COMP_CWORD-1}\" \"$cur\"))\n",
+		prog)
+	fmt.Fprint(buf, "\t\t;;\n\tesac\n\treturn 0\n}\n\n")
+	fmt.Fprintf(buf, "type -p %s >/dev/null && complete -F %s -o filenames %s\n",
+		prog, fn, prog)
+	return buf.String()
+}
+
+// zsh returns the equivalent of the bash completion script.
+func zsh(pages []*page) string {
+	prog := pages[0].path[0]
+	fn := "_" + strings.Replace(prog, "-", "_", -1)
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "#compdef %s\n# generated by %s doc\n\n", prog, prog)
+	fmt.Fprintf(buf, "%s() {\n", fn)
+	fmt.Fprint(buf, "\tcase \"${words[2,CURRENT-1]}\" in\n")
+	for _, p := range pages {
+		if len(p.subs) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "\t%q)\n", strings.Join(p.path[1:], " "))
+		fmt.Fprintf(buf, "\t\tcompadd -- %s\n", strings.Join(p.subs, " "))
+		fmt.Fprint(buf, "\t\t;;\n")
+	}
+	fmt.Fprint(buf, "\t*)\n")
+	fmt.Fprintf(buf, "\t\tcompadd -f -- ${(f)\"$(%s complete ${words[2,CURRENT-1]} \"${words[CURRENT]}\")\"}\n",
+		prog)
+	fmt.Fprint(buf, "\t\t;;\n\tesac\n}\n\n")
+	fmt.Fprintf(buf, "%s \"$@\"\n", fn)
+	return buf.String()
+}
@@ -0,0 +1,207 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package doc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/external/parms"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "doc" }
+
+func (*Command) Usage() string {
+	return "doc [-man DIR] [-markdown DIR] [-bash FILE] [-zsh FILE]"
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "generate man pages, markdown and completion scripts",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Write the documentation of every command, including those of the
+	nested command trees like "ip route", from their usage, apropos and
+	man text.
+
+OPTIONS
+	-man DIR
+		Write a section 1 roff page for each command, e.g.
+		DIR/goes-ip-route.1
+
+	-markdown DIR
+		Write a markdown page for each command and an index.md that
+		lists them all.
+
+	-bash FILE
+	-zsh FILE
+		Write a script completing the command names of each tree
+		and the rest with "goes complete".
+
+	A FILE of "-" is the standard output.
+
+EXAMPLES
+	goes doc -man debian/man -bash goes.bash-completion`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
+	parm, args := parms.New(args, "-man", "-markdown", "-bash", "-zsh")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	pages := walk(nil, c.g.String(), c.g)
+	did := false
+	if dir := parm.ByName["-man"]; len(dir) > 0 {
+		if err := writeMan(dir, pages); err != nil {
+			return err
+		}
+		did = true
+	}
+	if dir := parm.ByName["-markdown"]; len(dir) > 0 {
+		if err := writeMarkdown(dir, pages); err != nil {
+			return err
+		}
+		did = true
+	}
+	if fn := parm.ByName["-bash"]; len(fn) > 0 {
+		if err := writeFile(fn, bash(pages)); err != nil {
+			return err
+		}
+		did = true
+	}
+	if fn := parm.ByName["-zsh"]; len(fn) > 0 {
+		if err := writeFile(fn, zsh(pages)); err != nil {
+			return err
+		}
+		did = true
+	}
+	if !did {
+		return fmt.Errorf("-man, -markdown, -bash or -zsh: missing")
+	}
+	return nil
+}
+
+type maner interface {
+	Man() lang.Alt
+}
+
+// A page documents the command at the path of words from the top goes.
+type page struct {
+	path []string
+	v    cmd.Cmd
+	// subs are the names of the commands of a nested goes
+	subs []string
+}
+
+// name of the page, e.g. goes-ip-route
+func (p *page) name() string { return strings.Join(p.path, "-") }
+
+// title of the page, e.g. goes ip route
+func (p *page) title() string { return strings.Join(p.path, " ") }
+
+// catalogPath of the command in the message catalogs, e.g. ip route
+func (p *page) catalogPath() string { return strings.Join(p.path[1:], " ") }
+
+func (p *page) apropos() string {
+	return p.v.Apropos().Merge(p.catalogPath(), "apropos").String()
+}
+
+func (p *page) usage() string {
+	return strings.TrimSpace(lang.MergeString(p.v.Usage(),
+		p.catalogPath(), "usage"))
+}
+
+func (p *page) man() string {
+	if method, found := p.v.(maner); found {
+		return method.Man().Merge(p.catalogPath(), "man").String()
+	}
+	return ""
+}
+
+// walk returns the pages of the goes and, depth first, its commands. Names
+// of options like "-all" and the default command aren't pages of their own.
+func walk(path []string, name string, g *goes.Goes) []*page {
+	path = append(path[:len(path):len(path)], name)
+	top := &page{path: path, v: g}
+	pages := []*page{top}
+	for _, name := range g.Names() {
+		v, found := g.ByName[name]
+		if !found || len(name) == 0 || strings.HasPrefix(name, "-") {
+			continue
+		}
+		top.subs = append(top.subs, name)
+		if sub, ok := v.(*goes.Goes); ok {
+			pages = append(pages, walk(path, name, sub)...)
+			continue
+		}
+		pages = append(pages, &page{
+			path: append(path[:len(path):len(path)], name),
+			v:    v,
+		})
+	}
+	return pages
+}
+
+func writeFile(fn, s string) error {
+	if fn == "-" {
+		_, err := os.Stdout.WriteString(s)
+		return err
+	}
+	return ioutil.WriteFile(fn, []byte(s), 0644)
+}
+
+// sections of the man text of a command; those are lines in capitals
+// followed by tab indented text.
+func sections(man string) [][2]string {
+	var secs [][2]string
+	for _, line := range strings.Split(man, "\n") {
+		if isSection(line) {
+			secs = append(secs, [2]string{line, ""})
+			continue
+		}
+		if len(secs) == 0 {
+			if len(strings.TrimSpace(line)) == 0 {
+				continue
+			}
+			secs = append(secs, [2]string{"DESCRIPTION", ""})
+		}
+		secs[len(secs)-1][1] += strings.TrimPrefix(line, "\t") + "\n"
+	}
+	for i := range secs {
+		secs[i][1] = strings.Trim(secs[i][1], "\n")
+	}
+	return secs
+}
+
+func isSection(line string) bool {
+	if len(line) == 0 || line[0] == ' ' {
+		return false
+	}
+	for _, r := range line {
+		if (r < 'A' || r > 'Z') && r != ' ' {
+			return false
+		}
+	}
+	return true
+}
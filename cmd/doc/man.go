@@ -0,0 +1,76 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeMan writes a roff page for each command with its man text as is,
+// i.e. not filled.
+func writeMan(dir string, pages []*page) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	parents := make(map[string]*page)
+	for _, p := range pages {
+		for _, sub := range p.subs {
+			parents[p.name()+"-"+sub] = p
+		}
+	}
+	for _, p := range pages {
+		buf := new(bytes.Buffer)
+		fmt.Fprintf(buf, ".TH %s 1 \"\" \"%s\" \"%s Manual\"\n",
+			strings.ToUpper(p.name()), pages[0].title(),
+			pages[0].title())
+		fmt.Fprintf(buf, ".SH NAME\n%s \\- %s\n", roff(p.name()),
+			roff(p.apropos()))
+		fmt.Fprint(buf, ".SH SYNOPSIS\n.nf\n",
+			roff(p.usage()), "\n.fi\n")
+		for _, sec := range sections(p.man()) {
+			fmt.Fprint(buf, ".SH ", sec[0], "\n.nf\n", roff(sec[1]),
+				"\n.fi\n")
+		}
+		var also []string
+		if parent, found := parents[p.name()]; found {
+			also = append(also, parent.name())
+		}
+		for _, sub := range p.subs {
+			also = append(also, p.name()+"-"+sub)
+		}
+		if len(also) > 0 {
+			fmt.Fprint(buf, ".SH SEE ALSO\n")
+			for i, name := range also {
+				sep := ",\n"
+				if i == len(also)-1 {
+					sep = "\n"
+				}
+				fmt.Fprint(buf, ".BR ", roff(name), " (1)", sep)
+			}
+		}
+		fn := filepath.Join(dir, p.name()+".1")
+		if err := writeFile(fn, buf.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roff escapes backslashes and the control characters that start a line.
+func roff(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	s = strings.Replace(s, "-", `\-`, -1)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
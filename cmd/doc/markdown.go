@@ -0,0 +1,60 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeMarkdown writes a page for each command with its usage and man text
+// in code blocks, and an index of all pages.
+func writeMarkdown(dir string, pages []*page) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	index := new(bytes.Buffer)
+	fmt.Fprint(index, "# ", pages[0].title(), "\n\n")
+	fmt.Fprint(index, "| Command | Description |\n|---|---|\n")
+	for _, p := range pages {
+		fmt.Fprintf(index, "| [%s](%s.md) | %s |\n", markdown(p.title()),
+			url.PathEscape(p.name()), markdown(p.apropos()))
+		buf := new(bytes.Buffer)
+		fmt.Fprint(buf, "# ", markdown(p.title()), "\n\n",
+			markdown(p.apropos()), "\n\n")
+		fmt.Fprint(buf, "## SYNOPSIS\n\n```\n",
+			p.usage(), "\n```\n")
+		for _, sec := range sections(p.man()) {
+			fmt.Fprint(buf, "\n## ", sec[0], "\n\n```\n", sec[1],
+				"\n```\n")
+		}
+		if len(p.subs) > 0 {
+			fmt.Fprint(buf, "\n## COMMANDS\n\n")
+			for _, sub := range p.subs {
+				fmt.Fprintf(buf, "- [%s](%s.md)\n",
+					markdown(p.title()+" "+sub),
+					url.PathEscape(p.name()+"-"+sub))
+			}
+		}
+		fn := filepath.Join(dir, p.name()+".md")
+		if err := writeFile(fn, buf.String()); err != nil {
+			return err
+		}
+	}
+	return writeFile(filepath.Join(dir, "index.md"), index.String())
+}
+
+// markdown escapes the characters of a table cell or line of text that
+// would otherwise be formatting.
+func markdown(s string) string {
+	for _, c := range []string{`\`, "|", "*", "_", "`", "<", "[", "]"} {
+		s = strings.Replace(s, c, `\`+c, -1)
+	}
+	return s
+}
@@ -43,6 +43,8 @@ DESCRIPTION
 	-vendor-extension 
 		set, modify, or delete vendor sub-fields
 
+	-json	show the current eeprom fields as JSON
+
 	Without any args, show current eeprom configuation.`,
 	}
 }
@@ -70,12 +72,7 @@ func (c Command) Main(args ...string) error {
 	if len(args) > 0 {
 		return fmt.Errorf("%v: unexpected", args)
 	}
-	buf, err := Vendor.ReadBytes()
-	if err != nil {
-		return err
-	}
-	_, err = eeprom.Write(buf)
-	if err != nil {
+	if err := eeprom.read(); err != nil {
 		return err
 	}
 	for k, t := range flag.ByName {
@@ -87,7 +84,7 @@ func (c Command) Main(args ...string) error {
 		if len(s) == 0 {
 			continue
 		}
-		if err = eeprom.Set(k, s); err != nil {
+		if err := eeprom.Set(k, s); err != nil {
 			return err
 		}
 
@@ -119,6 +116,22 @@ func (c Command) Main(args ...string) error {
 	return err
 }
 
+// JSON returns the current eeprom fields by name, with the vendor
+// extension sub-fields named like those of Set.
+func (c Command) JSON(args ...string) (interface{}, error) {
+	var eeprom Eeprom
+	if c.Config != nil {
+		c.Config()
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%v: unexpected", args)
+	}
+	if err := eeprom.read(); err != nil {
+		return nil, err
+	}
+	return eeprom.Fields(), nil
+}
+
 func (Command) flags() []interface{} {
 	a := make([]interface{}, 1+len(Types))
 	a[0] = "-n"
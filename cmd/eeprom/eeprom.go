@@ -62,6 +62,26 @@ func (p *Eeprom) Equal(clone *Eeprom) error {
 	return p.Tlv.Equal(clone.Tlv)
 }
 
+// Fields returns the decoded fields by name, with the sub-fields of a
+// vendor extension that has them named like those of Set.
+func (p *Eeprom) Fields() map[string]string {
+	fields := map[string]string{
+		"Onie.Data":    p.Onie.Data.String(),
+		"Onie.Version": p.Onie.Version.String(),
+	}
+	for t, v := range p.Tlv {
+		method, found := v.(Fielder)
+		if t == VendorExtensionType && found {
+			for k, s := range method.Fields() {
+				fields[k] = s
+			}
+		} else {
+			fields[t.String()] = fmt.Sprint(v)
+		}
+	}
+	return fields
+}
+
 func (p *Eeprom) Set(name, s string) (err error) {
 	switch name {
 	case "Onie.Data":
@@ -99,6 +119,16 @@ func (p *Eeprom) String() string {
 	return buf.String()
 }
 
+// read the eeprom from the Vendor.
+func (p *Eeprom) read() error {
+	buf, err := Vendor.ReadBytes()
+	if err != nil {
+		return err
+	}
+	_, err = p.Write(buf)
+	return err
+}
+
 func (p *Eeprom) Write(buf []byte) (n int, err error) {
 	if p.Onie.Data == nil {
 		p.Onie.Data = new(OnieData)
@@ -60,64 +60,86 @@ func (m XtlvMap) Set(name, s string) error {
 	return nil
 }
 
+// Fields returns the decoded sub-fields by name.
+func (m XtlvMap) Fields() map[string]string {
+	fields := make(map[string]string)
+	if b, found := m[VendorExtensionType]; found {
+		fields[VendorExtensionType.String()] = b.String()
+		return fields
+	}
+	for _, t := range xtlvTypes {
+		if s, found := m.field(t); found {
+			fields[t.String()] = s
+		}
+	}
+	return fields
+}
+
 func (m XtlvMap) String() string {
 	if b, found := m[VendorExtensionType]; found {
 		return fmt.Sprintf("eeprom.VendorExtension: %q\n", b.String())
 	}
 	buf := new(bytes.Buffer)
-	for _, t := range []Type{
-		BoardTypeType,
-		ChassisTypeType,
-		SubTypeType,
-	} {
-		b, found := m[t]
-		if !found {
-			continue
-		}
-		x := b.Bytes()[0]
-		s := map[Type]map[byte]string{
-			BoardTypeType: map[byte]string{
-				0x00: "ToR",
-				0x01: "Broadwell 2-Core",
-				0x02: "Broadwell 4-Core",
-				0x03: "Broadwell 8-Core",
-				0x04: "MC",
-				0x05: "LC 32x100",
-				0x06: "MCB",
-				0x07: "Fan Controller",
-			},
-			ChassisTypeType: map[byte]string{
-				0x00: "ToR",
-				0x01: "4-slot",
-				0x02: "8-slot",
-				0x03: "16-slot",
-				0xff: "n/a",
-			},
-			SubTypeType: map[byte]string{
-				0x00: "beta",
-				0x01: "production",
-				0xff: "alpha",
-			},
-		}[t][x]
-		if len(s) == 0 {
-			s = fmt.Sprint("#0x", x)
-		}
-		fmt.Fprintf(buf, "eeprom.%s: %s\n", t, s)
-	}
-	for _, t := range []Type{
-		Tor1CpuPcbaSerialNumberType,
-		Tor1FanPcbaSerialNumberType,
-		Tor1MainPcbaSerialNumberType,
-	} {
-		b, found := m[t]
-		if !found || b.Len() == 0 {
-			continue
+	for _, t := range xtlvTypes {
+		if s, found := m.field(t); found {
+			fmt.Fprintf(buf, "eeprom.%s: %s\n", t, s)
 		}
-		fmt.Fprintf(buf, "eeprom.%s: %s\n", t, b)
 	}
 	return buf.String()
 }
 
+// xtlvTypes are the sub-fields in the order shown.
+var xtlvTypes = []Type{
+	BoardTypeType,
+	ChassisTypeType,
+	SubTypeType,
+	Tor1CpuPcbaSerialNumberType,
+	Tor1FanPcbaSerialNumberType,
+	Tor1MainPcbaSerialNumberType,
+}
+
+// field returns the decoded value of the sub-field, if any.
+func (m XtlvMap) field(t Type) (string, bool) {
+	b, found := m[t]
+	if !found {
+		return "", false
+	}
+	switch t {
+	case BoardTypeType, ChassisTypeType, SubTypeType:
+	default:
+		return b.String(), b.Len() > 0
+	}
+	x := b.Bytes()[0]
+	s := map[Type]map[byte]string{
+		BoardTypeType: map[byte]string{
+			0x00: "ToR",
+			0x01: "Broadwell 2-Core",
+			0x02: "Broadwell 4-Core",
+			0x03: "Broadwell 8-Core",
+			0x04: "MC",
+			0x05: "LC 32x100",
+			0x06: "MCB",
+			0x07: "Fan Controller",
+		},
+		ChassisTypeType: map[byte]string{
+			0x00: "ToR",
+			0x01: "4-slot",
+			0x02: "8-slot",
+			0x03: "16-slot",
+			0xff: "n/a",
+		},
+		SubTypeType: map[byte]string{
+			0x00: "beta",
+			0x01: "production",
+			0xff: "alpha",
+		},
+	}[t][x]
+	if len(s) == 0 {
+		s = fmt.Sprint("#0x", x)
+	}
+	return s, true
+}
+
 func (m XtlvMap) Write(buf []byte) (n int, err error) {
 	for len(buf) > 2 {
 		t := Type(buf[0])
@@ -82,6 +82,10 @@ type Deler interface {
 	Del(string)
 }
 
+type Fielder interface {
+	Fields() map[string]string
+}
+
 type Reseter interface {
 	Reset()
 }
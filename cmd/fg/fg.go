@@ -0,0 +1,57 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package fg
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "fg" }
+
+func (*Command) Usage() string { return "fg [%JOB]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "continue a job in the foreground",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Continue the given, or current, job with the terminal and wait for it
+	to finish or stop again. This requires an interactive cli.`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	var spec string
+	switch len(args) {
+	case 0:
+	case 1:
+		spec = args[0]
+	default:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	j, err := c.g.FindJob(spec)
+	if err != nil {
+		return err
+	}
+	fmt.Println(j.Text)
+	return c.g.Foreground(j)
+}
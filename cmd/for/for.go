@@ -153,12 +153,14 @@ func makeBlockFunc(g *goes.Goes, varName string,
 		g.EnvMap = make(map[string]string)
 	}
 	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		g.Status = nil
 		for _, word := range wordList {
-			for _, str := range word.Expand() {
+			for _, str := range word.Fields(g.Getenv, g.Cmdsubst) {
 				g.EnvMap[varName] = str
-				err := runList(doList, stdin, stdout, stderr)
-				if err != nil {
-					fmt.Fprintln(stderr, err)
+				stop, err := g.Loop(runList(doList, stdin, stdout,
+					stderr))
+				if stop {
+					return err
 				}
 				if g.Status != nil {
 					if g.Status.Error() == "signal: interrupt" {
@@ -42,6 +42,12 @@ func (Command) String() string    { return Name }
 func (Command) Usage() string     { return Usage }
 
 func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
+	// the source text is kept as the Definition
+	text := source{ReadWriter: g.Catline, lines: []string{ls.Quote()}}
+	if g.Catline != nil {
+		g.Catline = &text
+		defer func() { g.Catline = text.ReadWriter }()
+	}
 	cl := ls.Cmds[0]
 	// function name { definition ... ; }
 	if len(cl.Cmds) < 2 {
@@ -102,12 +108,16 @@ func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(s
 		for _, runent := range funList {
 			err := runent(stdin, stdout, stderr)
 			if err != nil {
-				return err
+				return g.Return(err)
 			}
 		}
 		return nil
 	}
-	f := goes.Function{Name: name, RunFun: runfun}
+	f := goes.Function{
+		Name:       name,
+		Definition: text.lines,
+		RunFun:     runfun,
+	}
 
 	deffun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 		if g.FunctionMap == nil {
@@ -122,3 +132,17 @@ func (Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(s
 func (Command) Main(args ...string) error {
 	return errors.New("internal error")
 }
+
+// source records the lines of a definition read from Catline.
+type source struct {
+	io.ReadWriter
+	lines []string
+}
+
+func (s *source) Read(p []byte) (n int, err error) {
+	n, err = s.ReadWriter.Read(p)
+	if n > 0 {
+		s.lines = append(s.lines, string(p[:n]))
+	}
+	return
+}
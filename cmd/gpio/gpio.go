@@ -80,3 +80,35 @@ func (c Command) Main(args ...string) error {
 	}
 	return nil
 }
+
+// A Pin is the JSON object of a GPIO pin value.
+type Pin struct {
+	Name  string `json:"name"`
+	Value bool   `json:"value"`
+	Err   string `json:"error,omitempty"`
+}
+
+// JSON returns the values of all or the given pins.
+func (Command) JSON(args ...string) (interface{}, error) {
+	names := args
+	if len(names) == 0 {
+		for name := range gpio.AllPins() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	pins := make([]Pin, 0, len(names))
+	for _, name := range names {
+		pin, found := gpio.FindPin(name)
+		if !found {
+			return nil, fmt.Errorf("%s: not found", name)
+		}
+		v, err := pin.Value()
+		p := Pin{Name: name, Value: v}
+		if err != nil {
+			p.Err = err.Error()
+		}
+		pins = append(pins, p)
+	}
+	return pins, nil
+}
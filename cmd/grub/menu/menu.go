@@ -212,6 +212,12 @@ func (c *Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List,
 				c.R.CurrentMenu = saved
 			}()
 		}
+		// an entry fails with its first failing command
+		errexit := g.ErrExit
+		g.ErrExit = true
+		defer func() {
+			g.ErrExit = errexit
+		}()
 		for _, runent := range funList {
 			err := runent(stdin, stdout, stderr)
 			if err != nil {
@@ -5,6 +5,7 @@
 package hwait
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -26,7 +27,11 @@ func (Command) Apropos() lang.Alt {
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (c Command) Main(args ...string) error {
+	return c.MainContext(context.Background(), args...)
+}
+
+func (Command) MainContext(ctx context.Context, args ...string) error {
 	n := time.Duration(3)
 	switch len(args) {
 	case 0:
@@ -43,7 +48,8 @@ func (Command) Main(args ...string) error {
 	default:
 		return fmt.Errorf("%v: unexpected", args[4:])
 	}
-	return redis.Hwait(args[0], args[1], args[2], n*time.Second)
+	return redis.HwaitContext(ctx, args[0], args[1], args[2],
+		n*time.Second)
 }
 
 func (Command) Complete(args ...string) []string {
@@ -0,0 +1,155 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package i18n
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/external/flags"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "i18n" }
+
+func (*Command) Usage() string { return "i18n extract [-po]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "extract the text of commands for translation",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Print the English apropos, man and usage text of every command as
+	a JSON message catalog, or with -po, a gettext template. The
+	translation of such a catalog in a file of
+	/usr/share/goes/locale/LANG/ is shown instead of the compiled text
+	when LANG is the preferred language.
+
+	Each entry is that of a command path and field, e.g. "ip route" and
+	"apropos". The path of goes itself is "".
+
+EXAMPLES
+	goes i18n extract -po > goes.pot
+	msginit -i goes.pot -l fr_FR -o /usr/share/goes/locale/fr_FR/goes.po`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-po")
+	switch {
+	case len(args) == 0:
+		return fmt.Errorf("extract: missing")
+	case args[0] != "extract":
+		return fmt.Errorf("%s: unknown", args[0])
+	case len(args) > 1:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	catalog := make(lang.Catalog)
+	extract(catalog, nil, c.g)
+	if flag.ByName["-po"] {
+		_, err := os.Stdout.WriteString(po(catalog))
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(catalog)
+}
+
+type maner interface {
+	Man() lang.Alt
+}
+
+// extract adds the English text of the goes and its commands, with those
+// of nested goes, to the catalog. Options like "-all" and the default
+// command are left out.
+func extract(catalog lang.Catalog, path []string, g *goes.Goes) {
+	add(catalog, path, g)
+	for _, name := range g.Names() {
+		v, found := g.ByName[name]
+		if !found || len(name) == 0 || strings.HasPrefix(name, "-") {
+			continue
+		}
+		subpath := append(path[:len(path):len(path)], name)
+		if sub, ok := v.(*goes.Goes); ok {
+			extract(catalog, subpath, sub)
+		} else {
+			add(catalog, subpath, v)
+		}
+	}
+}
+
+func add(catalog lang.Catalog, path []string, v cmd.Cmd) {
+	fields := map[string]string{
+		"apropos": v.Apropos()[lang.EnUS],
+		"usage":   v.Usage(),
+	}
+	if method, found := v.(maner); found {
+		fields["man"] = method.Man()[lang.EnUS]
+	}
+	for field, s := range fields {
+		if len(s) == 0 {
+			delete(fields, field)
+		}
+	}
+	catalog[strings.Join(path, " ")] = fields
+}
+
+// po returns the gettext template of the catalog.
+func po(catalog lang.Catalog) string {
+	paths := make([]string, 0, len(catalog))
+	for path := range catalog {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	buf := new(bytes.Buffer)
+	fmt.Fprint(buf, "msgid \"\"\n",
+		"msgstr \"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	for _, path := range paths {
+		for _, field := range []string{"apropos", "usage", "man"} {
+			s, found := catalog[path][field]
+			if !found {
+				continue
+			}
+			fmt.Fprint(buf, "\nmsgctxt ", strconv.Quote(path+"|"+field),
+				"\nmsgid ", poString(s), "\nmsgstr \"\"\n")
+		}
+	}
+	return buf.String()
+}
+
+// poString quotes each line of a multi-line string on its own.
+func poString(s string) string {
+	if !strings.Contains(s, "\n") {
+		return strconv.Quote(s)
+	}
+	lines := strings.SplitAfter(s, "\n")
+	quoted := []string{`""`}
+	for _, line := range lines {
+		if len(line) > 0 {
+			quoted = append(quoted, strconv.Quote(line))
+		}
+	}
+	return strings.Join(quoted, "\n")
+}
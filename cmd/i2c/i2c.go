@@ -6,6 +6,7 @@
 package i2c
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -49,7 +50,11 @@ DESCRIPTION
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (c Command) Main(args ...string) error {
+	return c.MainContext(context.Background(), args...)
+}
+
+func (Command) MainContext(ctx context.Context, args ...string) error {
 	var (
 		sd         i2c.SMBusData
 		b, a, d, w uint8
@@ -266,7 +271,14 @@ func (Command) Main(args ...string) error {
 			ascii = ""
 		}
 		if rw == i2c.Write && writeDelay > 0 {
-			time.Sleep(time.Second * time.Duration(writeDelay))
+			timer := time.NewTimer(time.Second *
+				time.Duration(writeDelay))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
 		}
 	}
 	fmt.Println(t)
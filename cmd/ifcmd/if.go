@@ -130,13 +130,17 @@ func runList(pipeline []func(stdin io.Reader, stdout io.Writer, stderr io.Writer
 
 func makeBlockFunc(g *goes.Goes, ifList, thenList, elseList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
 	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
-		err := runList(ifList, stdin, stdout, stderr)
-		if err == nil && g.Status == nil {
-			err = runList(thenList, stdin, stdout, stderr)
-		} else {
-			err = runList(elseList, stdin, stdout, stderr)
+		err := g.Condition(func() error {
+			return runList(ifList, stdin, stdout, stderr)
+		})
+		if err != nil {
+			return err
+		}
+		if g.Status == nil {
+			return runList(thenList, stdin, stdout, stderr)
 		}
-		return err
+		g.Status = nil
+		return runList(elseList, stdin, stdout, stderr)
 	}
 	return runfun, nil
 }
@@ -76,7 +76,70 @@ func (Command) Man() lang.Alt {
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (c Command) Main(args ...string) error {
+	opt, links, err := c.links(args)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		var ifla rtnl.Ifla
+		opt.ShowIfInfo(link.ifinfo)
+		ifla.Write(link.ifinfo)
+		if opt.Flags.ByName["-d"] {
+			if val := ifla[rtnl.IFLA_VFINFO_LIST]; len(val) > 0 {
+				rtnl.ForEachVfInfo(val, func(b []byte) {
+					opt.ShowIflaVf(b)
+				})
+			}
+		}
+		for _, b := range link.ifaddrs {
+			const withCacheInfo = true
+			opt.Println()
+			opt.Nprint(4)
+			opt.ShowIfAddr(b, withCacheInfo)
+		}
+		if opt.Flags.ByName["-s"] {
+			opt.Println()
+			val := ifla[rtnl.IFLA_STATS64]
+			if len(val) == 0 {
+				val = ifla[rtnl.IFLA_STATS]
+			}
+			if len(val) > 0 {
+				opt.ShowIfStats(val)
+			}
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func (c Command) JSON(args ...string) (interface{}, error) {
+	opt, links, err := c.links(args)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]options.Link, 0, len(links))
+	for _, link := range links {
+		v := options.NewLink(link.ifinfo)
+		if !opt.Flags.ByName["-s"] {
+			v.Stats64 = nil
+		}
+		for _, b := range link.ifaddrs {
+			v.AddrInfo = append(v.AddrInfo, options.NewAddr(b))
+		}
+		l = append(l, v)
+	}
+	return l, nil
+}
+
+// A link is a RTM_NEWLINK message with its RTM_NEWADDR messages.
+type link struct {
+	ifinfo  []byte
+	ifaddrs [][]byte
+}
+
+// links returns those with addresses selected by args.
+func (Command) links(args []string) (*options.Options, []link, error) {
 	var req []byte
 	var newifinfos [][]byte
 	var to string
@@ -93,7 +156,7 @@ func (Command) Main(args ...string) error {
 	if n := len(args); n == 1 {
 		opt.Parms.Set("dev", args[0])
 	} else if n > 1 {
-		return fmt.Errorf("%v: unexpected", args[1:])
+		return nil, nil, fmt.Errorf("%v: unexpected", args[1:])
 	}
 
 	label := opt.Parms.ByName["label"]
@@ -103,7 +166,7 @@ func (Command) Main(args ...string) error {
 	}
 	if name := opt.Parms.ByName["type"]; len(name) > 0 {
 		if val, found := rtnl.ArphrdByName[name]; !found {
-			return fmt.Errorf("type: %s: unknown", name)
+			return nil, nil, fmt.Errorf("type: %s: unknown", name)
 		} else {
 			arphrd = val
 		}
@@ -111,11 +174,11 @@ func (Command) Main(args ...string) error {
 	if to := opt.Parms.ByName["to"]; len(to) > 0 {
 		slash := strings.Index(to, "/")
 		if slash < 0 || slash == 0 || slash == len(to)-1 {
-			return fmt.Errorf("to: %s: invalid", to)
+			return nil, nil, fmt.Errorf("to: %s: invalid", to)
 		}
 		_, err := fmt.Sscan(to[slash+1:], &prefix)
 		if err != nil {
-			return fmt.Errorf("to: prefix: %s: %v",
+			return nil, nil, fmt.Errorf("to: prefix: %s: %v",
 				to[slash+1:], err)
 		}
 		to = to[:slash]
@@ -124,13 +187,13 @@ func (Command) Main(args ...string) error {
 		var found bool
 		rtscope, found = rtnl.RtScopeByName[name]
 		if !found {
-			return fmt.Errorf("scope: %s: unknown", name)
+			return nil, nil, fmt.Errorf("scope: %s: unknown", name)
 		}
 	}
 
 	sock, err := nl.NewSock()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer sock.Close()
 
@@ -146,7 +209,7 @@ func (Command) Main(args ...string) error {
 		},
 		nl.Attr{Type: rtnl.IFLA_EXT_MASK, Value: rtnl.RTEXT_FILTER_VF},
 	); err != nil {
-		return err
+		return nil, nil, err
 	} else if err = sr.UntilDone(req, func(b []byte) {
 		var ifla rtnl.Ifla
 		if nl.HdrPtr(b).Type != rtnl.RTM_NEWLINK {
@@ -179,7 +242,7 @@ func (Command) Main(args ...string) error {
 		}
 		newifinfos = append(newifinfos, b)
 	}); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	ifaddrlistByIndex := make(map[uint32][][]byte)
@@ -193,7 +256,7 @@ func (Command) Main(args ...string) error {
 				Family: af,
 			},
 		); err != nil {
-			return err
+			return nil, nil, err
 		} else if err = sr.UntilDone(req, func(b []byte) {
 			var ifa rtnl.Ifa
 			if nl.HdrPtr(b).Type != rtnl.RTM_NEWADDR {
@@ -227,12 +290,12 @@ func (Command) Main(args ...string) error {
 			}
 			ifaddrlistByIndex[idx] = ifaddrlist
 		}); err != nil {
-			return err
+			return nil, nil, err
 		}
 	}
 
+	var links []link
 	for _, ifinfo := range newifinfos {
-		var ifla rtnl.Ifla
 		msg := rtnl.IfInfoMsgPtr(ifinfo)
 		if mindex != -1 {
 			if msg.Index != mindex {
@@ -243,34 +306,9 @@ func (Command) Main(args ...string) error {
 		if !found || len(ifaddrlist) == 0 {
 			continue
 		}
-		opt.ShowIfInfo(ifinfo)
-		ifla.Write(ifinfo)
-		if opt.Flags.ByName["-d"] {
-			if val := ifla[rtnl.IFLA_VFINFO_LIST]; len(val) > 0 {
-				rtnl.ForEachVfInfo(val, func(b []byte) {
-					opt.ShowIflaVf(b)
-				})
-			}
-		}
-		for _, b := range ifaddrlist {
-			const withCacheInfo = true
-			opt.Println()
-			opt.Nprint(4)
-			opt.ShowIfAddr(b, withCacheInfo)
-		}
-		if opt.Flags.ByName["-s"] {
-			opt.Println()
-			val := ifla[rtnl.IFLA_STATS64]
-			if len(val) == 0 {
-				val = ifla[rtnl.IFLA_STATS]
-			}
-			if len(val) > 0 {
-				opt.ShowIfStats(val)
-			}
-		}
-		fmt.Println()
+		links = append(links, link{ifinfo, ifaddrlist})
 	}
-	return nil
+	return opt, links, nil
 }
 
 func (Command) Complete(args ...string) (list []string) {
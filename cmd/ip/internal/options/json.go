@@ -0,0 +1,248 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package options
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/platinasystems/goes/cmd/ip/internal/group"
+	"github.com/platinasystems/goes/internal/nl"
+	"github.com/platinasystems/goes/internal/nl/rtnl"
+)
+
+// The JSON output of the ip show commands are these objects named like
+// those of iproute2.
+
+type Link struct {
+	Index     int32      `json:"ifindex"`
+	Name      string     `json:"ifname,omitempty"`
+	Flags     []string   `json:"flags"`
+	MTU       uint32     `json:"mtu,omitempty"`
+	Qdisc     string     `json:"qdisc,omitempty"`
+	OperState string     `json:"operstate,omitempty"`
+	LinkMode  string     `json:"linkmode,omitempty"`
+	Group     string     `json:"group,omitempty"`
+	TxQLen    uint32     `json:"txqlen,omitempty"`
+	LinkType  string     `json:"link_type"`
+	Address   string     `json:"address,omitempty"`
+	Broadcast string     `json:"broadcast,omitempty"`
+	Stats64   *LinkStats `json:"stats64,omitempty"`
+	AddrInfo  []Addr     `json:"addr_info,omitempty"`
+}
+
+type LinkStats struct {
+	Rx struct {
+		Bytes     uint64 `json:"bytes"`
+		Packets   uint64 `json:"packets"`
+		Errors    uint64 `json:"errors"`
+		Dropped   uint64 `json:"dropped"`
+		OverErrs  uint64 `json:"over_errors"`
+		Multicast uint64 `json:"multicast"`
+	} `json:"rx"`
+	Tx struct {
+		Bytes         uint64 `json:"bytes"`
+		Packets       uint64 `json:"packets"`
+		Errors        uint64 `json:"errors"`
+		Dropped       uint64 `json:"dropped"`
+		CarrierErrors uint64 `json:"carrier_errors"`
+		Collisions    uint64 `json:"collisions"`
+	} `json:"tx"`
+}
+
+type Addr struct {
+	Family            string   `json:"family"`
+	Local             string   `json:"local"`
+	PrefixLen         uint8    `json:"prefixlen"`
+	Scope             string   `json:"scope"`
+	Flags             []string `json:"flags,omitempty"`
+	Label             string   `json:"label,omitempty"`
+	ValidLifeTime     *uint32  `json:"valid_life_time,omitempty"`
+	PreferredLifeTime *uint32  `json:"preferred_life_time,omitempty"`
+}
+
+type Route struct {
+	Dst      string `json:"dst"`
+	Src      string `json:"src,omitempty"`
+	Gateway  string `json:"gateway,omitempty"`
+	Dev      string `json:"dev,omitempty"`
+	Table    string `json:"table,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Scope    string `json:"scope"`
+	PrefSrc  string `json:"prefsrc,omitempty"`
+	Metric   uint32 `json:"metric,omitempty"`
+	Mark     uint32 `json:"mark,omitempty"`
+}
+
+type Neigh struct {
+	Dst    string   `json:"dst"`
+	Dev    string   `json:"dev"`
+	LLAddr string   `json:"lladdr,omitempty"`
+	Probes uint32   `json:"probes,omitempty"`
+	State  []string `json:"state"`
+}
+
+// NewLink returns the object of a RTM_NEWLINK message with its stats.
+func NewLink(b []byte) Link {
+	var ifla rtnl.Ifla
+	ifla.Write(b)
+	msg := rtnl.IfInfoMsgPtr(b)
+	link := Link{
+		Index:    msg.Index,
+		Flags:    IfFlags(msg.Flags),
+		LinkType: rtnl.ArphrdName[msg.Type],
+	}
+	if val := ifla[rtnl.IFLA_IFNAME]; len(val) > 0 {
+		link.Name = nl.Kstring(val)
+	}
+	if val := ifla[rtnl.IFLA_MTU]; len(val) > 0 {
+		link.MTU = nl.Uint32(val)
+	}
+	if val := ifla[rtnl.IFLA_QDISC]; len(val) > 0 {
+		link.Qdisc = nl.Kstring(val)
+	}
+	if val := ifla[rtnl.IFLA_OPERSTATE]; len(val) > 0 {
+		link.OperState = rtnl.IfOperName[nl.Uint8(val)]
+	}
+	if val := ifla[rtnl.IFLA_LINKMODE]; len(val) > 0 {
+		link.LinkMode = rtnl.IfLinkModeName[nl.Uint8(val)]
+	}
+	if val := ifla[rtnl.IFLA_GROUP]; len(val) > 0 {
+		link.Group = group.Name(nl.Uint32(val))
+	}
+	if val := ifla[rtnl.IFLA_TXQLEN]; len(val) > 0 {
+		link.TxQLen = nl.Uint32(val)
+	}
+	if val := ifla[rtnl.IFLA_ADDRESS]; len(val) > 0 {
+		link.Address = net.HardwareAddr(val).String()
+	}
+	if val := ifla[rtnl.IFLA_BROADCAST]; len(val) > 0 {
+		link.Broadcast = net.HardwareAddr(val).String()
+	}
+	val := ifla[rtnl.IFLA_STATS64]
+	if len(val) == 0 {
+		val = ifla[rtnl.IFLA_STATS]
+	}
+	if stats, ok := ifStats64(val); ok {
+		link.Stats64 = &LinkStats{}
+		link.Stats64.Rx.Bytes = stats[rtnl.Rx_bytes]
+		link.Stats64.Rx.Packets = stats[rtnl.Rx_packets]
+		link.Stats64.Rx.Errors = stats[rtnl.Rx_errors]
+		link.Stats64.Rx.Dropped = stats[rtnl.Rx_dropped]
+		link.Stats64.Rx.OverErrs = stats[rtnl.Rx_over_errors]
+		link.Stats64.Rx.Multicast = stats[rtnl.Multicast]
+		link.Stats64.Tx.Bytes = stats[rtnl.Tx_bytes]
+		link.Stats64.Tx.Packets = stats[rtnl.Tx_packets]
+		link.Stats64.Tx.Errors = stats[rtnl.Tx_errors]
+		link.Stats64.Tx.Dropped = stats[rtnl.Tx_dropped]
+		link.Stats64.Tx.CarrierErrors = stats[rtnl.Tx_carrier_errors]
+		link.Stats64.Tx.Collisions = stats[rtnl.Collisions]
+	}
+	return link
+}
+
+// NewAddr returns the object of a RTM_NEWADDR message.
+func NewAddr(b []byte) Addr {
+	var ifa rtnl.Ifa
+	ifa.Write(b)
+	msg := rtnl.IfAddrMsgPtr(b)
+	flags, _ := ifaFlags(b)
+	addr := Addr{
+		Family:    rtnl.AfName(msg.Family),
+		Local:     net.IP(ifa[rtnl.IFA_ADDRESS]).String(),
+		PrefixLen: msg.Prefixlen,
+		Scope:     rtnl.RtScopeName[msg.Scope],
+		Flags:     flags,
+	}
+	if val := ifa[rtnl.IFA_LABEL]; len(val) > 0 {
+		addr.Label = nl.Kstring(val)
+	}
+	if ci := rtnl.IfaCacheInfoPtr(ifa[rtnl.IFA_CACHEINFO]); ci != nil {
+		valid, preferred := ci.Valid, ci.Prefered
+		addr.ValidLifeTime = &valid
+		addr.PreferredLifeTime = &preferred
+	}
+	return addr
+}
+
+// NewRoute returns the object of a RTM_NEWROUTE message.
+func NewRoute(b []byte) Route {
+	var rta rtnl.Rta
+	rta.Write(b)
+	msg := rtnl.RtMsgPtr(b)
+	route := Route{
+		Dst:   "default",
+		Scope: rtnl.RtScopeName[msg.Scope],
+	}
+	if val := rta[rtnl.RTA_DST]; len(val) > 0 {
+		route.Dst = net.IP(val).String()
+		if msg.Dst_len != rtnl.AfBits[msg.Family] {
+			route.Dst += fmt.Sprint("/", msg.Dst_len)
+		}
+	} else if msg.Dst_len > 0 {
+		route.Dst = fmt.Sprint("0/", msg.Dst_len)
+	}
+	if val := rta[rtnl.RTA_SRC]; len(val) > 0 {
+		route.Src = net.IP(val).String()
+		if msg.Src_len != rtnl.AfBits[msg.Family] {
+			route.Src += fmt.Sprint("/", msg.Src_len)
+		}
+	} else if msg.Src_len > 0 {
+		route.Src = fmt.Sprint("0/", msg.Src_len)
+	}
+	if val := rta[rtnl.RTA_GATEWAY]; len(val) > 0 {
+		route.Gateway = net.IP(val).String()
+	}
+	if val := rta[rtnl.RTA_OIF]; len(val) > 0 {
+		route.Dev = ifName(nl.Int32(val))
+	}
+	if val := rta[rtnl.RTA_TABLE]; len(val) > 0 {
+		route.Table = rtnl.RtTableName(nl.Uint32(val))
+	}
+	if msg.Protocol != rtnl.RTPROT_UNSPEC {
+		route.Protocol = rtnl.RtProtName[msg.Protocol]
+	}
+	if val := rta[rtnl.RTA_PREFSRC]; len(val) > 0 {
+		route.PrefSrc = net.IP(val).String()
+	}
+	if val := rta[rtnl.RTA_PRIORITY]; len(val) > 0 {
+		route.Metric = nl.Uint32(val)
+	}
+	if val := rta[rtnl.RTA_MARK]; len(val) > 0 {
+		route.Mark = nl.Uint32(val)
+	}
+	return route
+}
+
+// NewNeigh returns the object of a RTM_NEWNEIGH message and false if it
+// doesn't have a destination.
+func NewNeigh(b []byte) (Neigh, bool) {
+	var nda rtnl.Nda
+	nda.Write(b)
+	msg := rtnl.NdMsgPtr(b)
+	dst := nda[rtnl.NDA_DST]
+	if len(dst) == 0 {
+		return Neigh{}, false
+	}
+	neigh := Neigh{
+		Dst:   net.IP(dst).String(),
+		Dev:   ifName(msg.Index),
+		State: ndStates(msg.State),
+	}
+	if lladdr := nda[rtnl.NDA_LLADDR]; len(lladdr) >= 6 {
+		neigh.LLAddr = net.HardwareAddr(lladdr[:6]).String()
+	}
+	if val := nda[rtnl.NDA_PROBES]; len(val) > 0 {
+		neigh.Probes = nl.Uint32(val)
+	}
+	return neigh, true
+}
+
+func ifName(index int32) string {
+	if name, found := rtnl.If.NameByIndex[index]; found {
+		return name
+	}
+	return fmt.Sprint(index)
+}
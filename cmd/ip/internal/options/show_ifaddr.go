@@ -13,8 +13,41 @@ import (
 )
 
 func (opt *Options) ShowIfAddr(b []byte, withCacheInfo bool) {
+	var ifa rtnl.Ifa
+	ifa.Write(b)
+	msg := rtnl.IfAddrMsgPtr(b)
+
+	opt.Print(rtnl.AfName(msg.Family), " ")
+	ip := net.IP(ifa[rtnl.IFA_ADDRESS])
+	opt.Print(ip, "/", msg.Prefixlen)
+	opt.Print(" scope ", rtnl.RtScopeName[msg.Scope])
+
+	names, ifaf := ifaFlags(b)
+	for _, name := range names {
+		opt.Print(" ", name)
+	}
+	if ifaf != 0 {
+		fmt.Printf(" flags %#x", ifaf)
+	}
+
+	if val := ifa[rtnl.IFA_LABEL]; len(val) > 0 {
+		opt.Print(" ", nl.Kstring(val))
+	}
+	if withCacheInfo {
+		ci := rtnl.IfaCacheInfoPtr(ifa[rtnl.IFA_CACHEINFO])
+		if ci != nil {
+			opt.Println()
+			opt.Nprint(7)
+			opt.showIfaCacheInfo(ci)
+		}
+	}
+}
+
+// ifaFlags returns the names of the address flags along with any others.
+func ifaFlags(b []byte) ([]string, uint32) {
 	var ifa rtnl.Ifa
 	var ifaf uint32
+	var names []string
 	ifa.Write(b)
 	msg := rtnl.IfAddrMsgPtr(b)
 
@@ -24,17 +57,12 @@ func (opt *Options) ShowIfAddr(b []byte, withCacheInfo bool) {
 		ifaf = uint32(msg.Flags)
 	}
 
-	opt.Print(rtnl.AfName(msg.Family), " ")
-	ip := net.IP(ifa[rtnl.IFA_ADDRESS])
-	opt.Print(ip, "/", msg.Prefixlen)
-	opt.Print(" scope ", rtnl.RtScopeName[msg.Scope])
-
 	if (ifaf & uint32(rtnl.IFA_F_SECONDARY)) ==
 		uint32(rtnl.IFA_F_SECONDARY) {
 		if msg.Family == rtnl.AF_INET {
-			opt.Print(" secondary")
+			names = append(names, "secondary")
 		} else {
-			opt.Print(" temporary")
+			names = append(names, "temporary")
 		}
 	}
 	for _, x := range []struct {
@@ -54,28 +82,14 @@ func (opt *Options) ShowIfAddr(b []byte, withCacheInfo bool) {
 	} {
 		if x.not {
 			if (ifaf & x.flag) != x.flag {
-				opt.Print(" ", x.name)
+				names = append(names, x.name)
 			}
 		} else if (ifaf & x.flag) == x.flag {
-			opt.Print(" ", x.name)
+			names = append(names, x.name)
 		}
 		ifaf &= ^x.flag
 	}
-	if ifaf != 0 {
-		fmt.Printf(" flags %#x", ifaf)
-	}
-
-	if val := ifa[rtnl.IFA_LABEL]; len(val) > 0 {
-		opt.Print(" ", nl.Kstring(val))
-	}
-	if withCacheInfo {
-		ci := rtnl.IfaCacheInfoPtr(ifa[rtnl.IFA_CACHEINFO])
-		if ci != nil {
-			opt.Println()
-			opt.Nprint(7)
-			opt.showIfaCacheInfo(ci)
-		}
-	}
+	return names, ifaf
 }
 
 func (opt *Options) showIfaCacheInfo(ci *rtnl.IfaCacheInfo) {
@@ -65,11 +65,20 @@ func (opt *Options) ShowIfInfo(b []byte) {
 }
 
 func (opt *Options) ShowIfFlags(iff uint32) {
-	var comma string
+	for i, name := range IfFlags(iff) {
+		if i > 0 {
+			opt.Print(",")
+		}
+		opt.Print(name)
+	}
+}
+
+// IfFlags returns the names of the interface flags.
+func IfFlags(iff uint32) []string {
+	names := []string{}
 	if (iff&rtnl.IFF_UP) == rtnl.IFF_UP &&
 		(iff&rtnl.IFF_RUNNING) != rtnl.IFF_RUNNING {
-		opt.Print("no-carrier")
-		comma = ","
+		names = append(names, "no-carrier")
 	}
 	for _, x := range []struct {
 		flag uint32
@@ -95,8 +104,8 @@ func (opt *Options) ShowIfFlags(iff uint32) {
 		{rtnl.IFF_ECHO, "echo"},
 	} {
 		if (iff & x.flag) == x.flag {
-			opt.Print(comma, x.name)
-			comma = ","
+			names = append(names, x.name)
 		}
 	}
+	return names
 }
@@ -7,17 +7,10 @@ package options
 import "github.com/platinasystems/goes/internal/nl/rtnl"
 
 func (opt *Options) ShowIfStats(val []byte) {
-	var ifstats64 rtnl.IfStats64
 	opt.Println()
 	opt.Nprint(4)
-	if len(val) >= rtnl.SizeofIfStats64 {
-		ifstats64 = *rtnl.IfStats64Attr(val)
-	} else if len(val) >= rtnl.SizeofIfStats {
-		ifstats32 := *rtnl.IfStatsAttr(val)
-		for i := 0; i < rtnl.N_link_stat; i++ {
-			ifstats64[i] = uint64(ifstats32[i])
-		}
-	} else {
+	ifstats64, ok := ifStats64(val)
+	if !ok {
 		opt.Print("can't show these stats: ", val)
 		return
 	}
@@ -39,3 +32,19 @@ func (opt *Options) ShowIfStats(val []byte) {
 	opt.Nprint(8, Stat(ifstats64[rtnl.Tx_carrier_errors]))
 	opt.Print(Stat(ifstats64[rtnl.Collisions]))
 }
+
+// ifStats64 returns the IFLA_STATS64 or IFLA_STATS value as 64-bit counters
+// and false if it's too short for either.
+func ifStats64(val []byte) (ifstats64 rtnl.IfStats64, ok bool) {
+	if len(val) >= rtnl.SizeofIfStats64 {
+		ifstats64 = *rtnl.IfStats64Attr(val)
+	} else if len(val) >= rtnl.SizeofIfStats {
+		ifstats32 := *rtnl.IfStatsAttr(val)
+		for i := 0; i < rtnl.N_link_stat; i++ {
+			ifstats64[i] = uint64(ifstats32[i])
+		}
+	} else {
+		return ifstats64, false
+	}
+	return ifstats64, true
+}
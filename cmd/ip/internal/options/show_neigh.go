@@ -49,25 +49,34 @@ func (opt *Options) ShowNeigh(b []byte) {
 			opt.Print(" probes ", nl.Uint32(val))
 		}
 	}
-	{
-		sep := " "
-		for _, x := range []struct {
-			flag uint16
-			name string
-		}{
-			{rtnl.NUD_INCOMPLETE, "incomplete"},
-			{rtnl.NUD_REACHABLE, "reachable"},
-			{rtnl.NUD_STALE, "stale"},
-			{rtnl.NUD_DELAY, "delay"},
-			{rtnl.NUD_PROBE, "probe"},
-			{rtnl.NUD_FAILED, "failed"},
-			{rtnl.NUD_NOARP, "noarp"},
-			{rtnl.NUD_PERMANENT, "permanent"},
-		} {
-			if (msg.State & x.flag) == x.flag {
-				opt.Print(sep, x.name)
-				sep = ","
-			}
+	for i, name := range ndStates(msg.State) {
+		sep := ","
+		if i == 0 {
+			sep = " "
+		}
+		opt.Print(sep, name)
+	}
+}
+
+// ndStates returns the names of the neighbor states.
+func ndStates(state uint16) []string {
+	names := []string{}
+	for _, x := range []struct {
+		flag uint16
+		name string
+	}{
+		{rtnl.NUD_INCOMPLETE, "incomplete"},
+		{rtnl.NUD_REACHABLE, "reachable"},
+		{rtnl.NUD_STALE, "stale"},
+		{rtnl.NUD_DELAY, "delay"},
+		{rtnl.NUD_PROBE, "probe"},
+		{rtnl.NUD_FAILED, "failed"},
+		{rtnl.NUD_NOARP, "noarp"},
+		{rtnl.NUD_PERMANENT, "permanent"},
+	} {
+		if (state & x.flag) == x.flag {
+			names = append(names, x.name)
 		}
 	}
+	return names
 }
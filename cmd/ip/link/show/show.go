@@ -43,7 +43,52 @@ func (Command) Man() lang.Alt {
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (c Command) Main(args ...string) error {
+	opt, links, err := c.links(args)
+	if err != nil {
+		return err
+	}
+	for _, b := range links {
+		var ifla rtnl.Ifla
+		opt.ShowIfInfo(b)
+		ifla.Write(b)
+		if opt.Flags.ByName["-s"] {
+			val := ifla[rtnl.IFLA_STATS64]
+			if len(val) == 0 {
+				val = ifla[rtnl.IFLA_STATS]
+			}
+			if len(val) > 0 {
+				opt.ShowIfStats(val)
+			}
+		}
+		if val := ifla[rtnl.IFLA_VFINFO_LIST]; len(val) > 0 {
+			rtnl.ForEachVfInfo(val, func(b []byte) {
+				opt.ShowIflaVf(b)
+			})
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func (c Command) JSON(args ...string) (interface{}, error) {
+	opt, links, err := c.links(args)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]options.Link, 0, len(links))
+	for _, b := range links {
+		link := options.NewLink(b)
+		if !opt.Flags.ByName["-s"] {
+			link.Stats64 = nil
+		}
+		l = append(l, link)
+	}
+	return l, nil
+}
+
+// links returns the RTM_NEWLINK messages selected by args in index order.
+func (Command) links(args []string) (*options.Options, [][]byte, error) {
 	var req []byte
 	var gid uint32 // default: 0
 	var newifinfos [][]byte
@@ -57,7 +102,7 @@ func (Command) Main(args ...string) error {
 	if n := len(args); n == 1 {
 		opt.Parms.Set("dev", args[0])
 	} else if n > 1 {
-		return fmt.Errorf("%v: unexpected", args[1:])
+		return nil, nil, fmt.Errorf("%v: unexpected", args[1:])
 	}
 
 	if vrf := opt.Parms.ByName["vrf"]; len(vrf) > 0 {
@@ -69,7 +114,7 @@ func (Command) Main(args ...string) error {
 	}
 	if name := opt.Parms.ByName["type"]; len(name) > 0 {
 		if val, found := rtnl.ArphrdByName[name]; !found {
-			return fmt.Errorf("type: %s: unknown", name)
+			return nil, nil, fmt.Errorf("type: %s: unknown", name)
 		} else {
 			arphrd = val
 		}
@@ -77,7 +122,7 @@ func (Command) Main(args ...string) error {
 
 	sock, err := nl.NewSock()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer sock.Close()
 
@@ -93,7 +138,7 @@ func (Command) Main(args ...string) error {
 		},
 		nl.Attr{Type: rtnl.IFLA_EXT_MASK, Value: rtnl.RTEXT_FILTER_VF},
 	); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err = sr.UntilDone(req, func(b []byte) {
 		var ifla rtnl.Ifla
@@ -134,11 +179,11 @@ func (Command) Main(args ...string) error {
 		}
 		newifinfos = append(newifinfos, b)
 	}); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if len(newifinfos) == 0 {
-		return fmt.Errorf("no info")
+		return nil, nil, fmt.Errorf("no info")
 	}
 
 	sort.Slice(newifinfos, func(i, j int) bool {
@@ -147,33 +192,17 @@ func (Command) Main(args ...string) error {
 		return iIndex < jIndex
 	})
 
+	links := newifinfos[:0]
 	for _, b := range newifinfos {
-		var ifla rtnl.Ifla
 		msg := rtnl.IfInfoMsgPtr(b)
 		if mindex != -1 {
 			if msg.Index != mindex {
 				continue
 			}
 		}
-		opt.ShowIfInfo(b)
-		ifla.Write(b)
-		if opt.Flags.ByName["-s"] {
-			val := ifla[rtnl.IFLA_STATS64]
-			if len(val) == 0 {
-				val = ifla[rtnl.IFLA_STATS]
-			}
-			if len(val) > 0 {
-				opt.ShowIfStats(val)
-			}
-		}
-		if val := ifla[rtnl.IFLA_VFINFO_LIST]; len(val) > 0 {
-			rtnl.ForEachVfInfo(val, func(b []byte) {
-				opt.ShowIflaVf(b)
-			})
-		}
-		fmt.Println()
+		links = append(links, b)
 	}
-	return nil
+	return opt, links, nil
 }
 
 func (Command) Complete(args ...string) (list []string) {
@@ -47,7 +47,34 @@ SEE ALSO
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (c Command) Main(args ...string) error {
+	opt, neighs, err := c.neighs(args)
+	if err != nil {
+		return err
+	}
+	for _, b := range neighs {
+		opt.ShowNeigh(b)
+		fmt.Println()
+	}
+	return nil
+}
+
+func (c Command) JSON(args ...string) (interface{}, error) {
+	_, neighs, err := c.neighs(args)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]options.Neigh, 0, len(neighs))
+	for _, b := range neighs {
+		if neigh, ok := options.NewNeigh(b); ok {
+			l = append(l, neigh)
+		}
+	}
+	return l, nil
+}
+
+// neighs returns the RTM_NEWNEIGH messages selected by args.
+func (Command) neighs(args []string) (*options.Options, [][]byte, error) {
 	var err error
 	var req []byte
 	var newneighs [][]byte
@@ -61,13 +88,13 @@ func (Command) Main(args ...string) error {
 	if n := len(args); n == 1 {
 		opt.Parms.Set("to", args[0])
 	} else if n > 1 {
-		return fmt.Errorf("%v: unexpected", args[1:])
+		return nil, nil, fmt.Errorf("%v: unexpected", args[1:])
 	}
 
 	if val := opt.Parms.ByName["to"]; len(val) > 0 {
 		toip, toipnet, err = net.ParseCIDR(val)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 	}
 
@@ -76,7 +103,7 @@ func (Command) Main(args ...string) error {
 		if val == "all" {
 			nud = rtnl.NUD_ALL
 		} else if v, found := rtnl.NudByName[val]; !found {
-			return fmt.Errorf("nud: %s: unknown", val)
+			return nil, nil, fmt.Errorf("nud: %s: unknown", val)
 		} else {
 			nud = v
 		}
@@ -84,14 +111,14 @@ func (Command) Main(args ...string) error {
 
 	sock, err := nl.NewSock()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer sock.Close()
 
 	sr := nl.NewSockReceiver(sock)
 
 	if err = rtnl.MakeIfMaps(sr); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	devidx := int32(-1)
@@ -99,7 +126,7 @@ func (Command) Main(args ...string) error {
 	if name := opt.Parms.ByName["dev"]; len(name) > 0 {
 		devidx, found = rtnl.If.IndexByName[name]
 		if !found {
-			return fmt.Errorf("dev: %s: not found", name)
+			return nil, nil, fmt.Errorf("dev: %s: not found", name)
 		}
 	}
 
@@ -107,7 +134,7 @@ func (Command) Main(args ...string) error {
 	if name := opt.Parms.ByName["vrf"]; len(name) > 0 {
 		vrfidx, found = rtnl.If.IndexByName[name]
 		if !found {
-			return fmt.Errorf("vrf: %s: not found", name)
+			return nil, nil, fmt.Errorf("vrf: %s: not found", name)
 		}
 	}
 
@@ -121,7 +148,7 @@ func (Command) Main(args ...string) error {
 				Family: af,
 			},
 		); err != nil {
-			return err
+			return nil, nil, err
 		} else if err = sr.UntilDone(req, func(b []byte) {
 			if nl.HdrPtr(b).Type != rtnl.RTM_NEWNEIGH {
 				return
@@ -170,7 +197,7 @@ func (Command) Main(args ...string) error {
 			}
 			newneighs = append(newneighs, b)
 		}); err != nil {
-			return err
+			return nil, nil, err
 		}
 	}
 
@@ -187,9 +214,5 @@ func (Command) Main(args ...string) error {
 			bytes.Compare(iNda[rtnl.NDA_DST], jNda[rtnl.NDA_DST])
 	})
 
-	for _, b := range newneighs {
-		opt.ShowNeigh(b)
-		fmt.Println()
-	}
-	return nil
+	return opt, newneighs, nil
 }
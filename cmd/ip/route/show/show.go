@@ -56,7 +56,33 @@ SEE ALSO
 }
 
 func (c Command) Main(args ...string) error {
+	opt, routes, err := c.routes(args)
+	if err != nil {
+		return err
+	}
+	for _, b := range routes {
+		opt.ShowRoute(b)
+		fmt.Println()
+	}
+	return nil
+}
+
+func (c Command) JSON(args ...string) (interface{}, error) {
+	_, routes, err := c.routes(args)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]options.Route, 0, len(routes))
+	for _, b := range routes {
+		l = append(l, options.NewRoute(b))
+	}
+	return l, nil
+}
+
+// routes returns the RTM_NEWROUTE messages selected by args.
+func (Command) routes(args []string) (*options.Options, [][]byte, error) {
 	var req []byte
+	var newroutes [][]byte
 	var to string
 	var prefix uint8
 
@@ -81,7 +107,7 @@ func (c Command) Main(args ...string) error {
 	if n := len(args); n == 1 {
 		opt.Parms.Set("to", args[0])
 	} else if n > 1 {
-		return fmt.Errorf("%v: unexpected", args[1:])
+		return nil, nil, fmt.Errorf("%v: unexpected", args[1:])
 	}
 
 	tbl := rtnl.RT_TABLE_MAIN
@@ -97,7 +123,7 @@ func (c Command) Main(args ...string) error {
 			if !found {
 				_, err := fmt.Sscan(tname, &tbl)
 				if err != nil {
-					return fmt.Errorf("table: %s: unknown",
+					return nil, nil, fmt.Errorf("table: %s: unknown",
 						tname)
 				}
 			}
@@ -107,11 +133,11 @@ func (c Command) Main(args ...string) error {
 		slash := strings.Index(to, "/")
 		if to != "default" {
 			if slash < 0 || slash == 0 || slash == len(to)-1 {
-				return fmt.Errorf("to: %s: invalid prefix", to)
+				return nil, nil, fmt.Errorf("to: %s: invalid prefix", to)
 			}
 			_, err := fmt.Sscan(to[slash+1:], &prefix)
 			if err != nil {
-				return fmt.Errorf("to: prefix: %s: %v",
+				return nil, nil, fmt.Errorf("to: prefix: %s: %v",
 					to[slash+1:], err)
 			}
 			to = to[:slash]
@@ -120,14 +146,14 @@ func (c Command) Main(args ...string) error {
 
 	sock, err := nl.NewSock()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer sock.Close()
 
 	sr := nl.NewSockReceiver(sock)
 
 	if err = rtnl.MakeIfMaps(sr); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	for _, af := range opt.Afs() {
@@ -140,7 +166,7 @@ func (c Command) Main(args ...string) error {
 				Family: af,
 			},
 		); err != nil {
-			return err
+			return nil, nil, err
 		} else if err = sr.UntilDone(req, func(b []byte) {
 			if nl.HdrPtr(b).Type != rtnl.RTM_NEWROUTE {
 				return
@@ -165,13 +191,12 @@ func (c Command) Main(args ...string) error {
 					return
 				}
 			}
-			opt.ShowRoute(b)
-			fmt.Println()
+			newroutes = append(newroutes, b)
 		}); err != nil {
-			return err
+			return nil, nil, err
 		}
 	}
-	return nil
+	return opt, newroutes, nil
 }
 
 func (Command) Complete(args ...string) (list []string) {
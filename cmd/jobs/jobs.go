@@ -0,0 +1,65 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/external/flags"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "jobs" }
+
+func (*Command) Usage() string { return "jobs [-l | -p]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "list background and stopped jobs",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	List the number, state and command of each job of the cli. Completed
+	jobs are listed once then removed.
+
+OPTIONS
+	-l	also list the process group id
+	-p	list only the process group id`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-l", "-p")
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	for _, j := range c.g.Jobs() {
+		switch {
+		case flag.ByName["-p"]:
+			fmt.Println(j.Pgid)
+		case flag.ByName["-l"]:
+			fmt.Printf("[%d]  %d %-8s %s\n", j.Id, j.Pgid, j.State(),
+				j.Text)
+		default:
+			fmt.Println(j)
+		}
+	}
+	c.g.ReapJobs()
+	return nil
+}
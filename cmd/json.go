@@ -0,0 +1,12 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package cmd
+
+// A JSONer is a command with machine readable output. With the -json
+// flag, goes prints the value returned by JSON instead of running Main.
+// JSON has the same arguments as Main less the flag.
+type JSONer interface {
+	JSON(...string) (interface{}, error)
+}
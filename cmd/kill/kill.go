@@ -11,6 +11,8 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/external/flags"
 	"github.com/platinasystems/goes/lang"
 )
@@ -53,19 +55,21 @@ var sigByOptName = map[string]syscall.Signal{
 	"-xfsz":   syscall.SIGXFSZ,
 }
 
-type Command struct{}
+type Command struct {
+	g *goes.Goes
+}
 
-func (Command) String() string { return "kill" }
+func (*Command) String() string { return "kill" }
 
-func (Command) Usage() string { return "kill [OPTION] [PID]..." }
+func (*Command) Usage() string { return "kill [OPTION] [PID | %JOB]..." }
 
-func (Command) Apropos() lang.Alt {
+func (*Command) Apropos() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: "signal a process",
 	}
 }
 
-func (Command) Man() lang.Alt {
+func (*Command) Man() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: `
 DESCRIPTION
@@ -77,10 +81,16 @@ DESCRIPTION
 	output.  A PID of -1 is special; it indicates all processes except the
 	kill process itself and init.
 
+	A '%JOB' specification signals every process of a cli job; see the
+	JOBS section of 'man cli'.
+
 OPTIONS
 	<PID> [...]
 		Send signal to every <PID> listed.
 
+	%<JOB> [...]
+		Send signal to every process of each listed job.
+
        -<NAME>
        -<NUMBER>
 		Specify the signal to be sent.
@@ -92,11 +102,18 @@ EXAMPLES
 		Kill all processes you can kill.
 
 	kill 123 543 2341 3453
-		Send the default signal, SIGTERM, to all those processes.`,
+		Send the default signal, SIGTERM, to all those processes.
+
+	kill -int %1
+		Interrupt the first cli job.`,
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (c *Command) Main(args ...string) error {
 	flag, args := flags.New(args, "-l")
 
 	sigByOptNumb := make(map[string]syscall.Signal)
@@ -172,6 +189,19 @@ func (Command) Main(args ...string) error {
 		}
 	}
 	for _, arg := range args {
+		if strings.HasPrefix(arg, "%") {
+			if c.g == nil {
+				return fmt.Errorf("%s: no job control", arg)
+			}
+			j, err := c.g.FindJob(arg)
+			if err != nil {
+				return err
+			}
+			if err = c.g.SignalJob(j, sig); err != nil {
+				return err
+			}
+			continue
+		}
 		pid, err := strconv.ParseInt(arg, 0, 0)
 		if err != nil {
 			return err
@@ -15,8 +15,8 @@ import (
 
 	"github.com/mattn/go-isatty"
 
-	"github.com/platinasystems/goes/external/flags"
-	"github.com/platinasystems/goes/external/parms"
+	"github.com/platinasystems/goes/external/options"
+	"github.com/platinasystems/goes/external/serial"
 	"github.com/platinasystems/goes/lang"
 	pldp "github.com/platinasystems/ldp"
 	"github.com/platinasystems/loopback"
@@ -27,7 +27,7 @@ type Command struct{}
 func (Command) String() string { return "ldp" }
 
 func (Command) Usage() string {
-	return "ldp [OPTION]... DEVICE"
+	return serial.Options.Usage("ldp", "[DEVICE]")
 }
 
 func (Command) Apropos() lang.Alt {
@@ -43,109 +43,16 @@ DESCRIPTION
 	ldp runs the Platina Link Diagnostic Protocol over the specified
 	serial device.
 
-OPTIONS
-	-baud BAUD
-		The valid baud rates are 300, 600, 1200, 2400,
-		4800, 9600, 19200, 38400, 57600, and 115200.
-
-	-parity PARITY
-		The valid parity options are "odd", "even" and default, "none".
-
-	-databits BITS
-		The valid bits per character are 5, 6, 7, and default, 8.
-
-	-stopbits BITS
-		The valid stop bits per character are 2 and default, 1.
-
-	-noinit
-		Don't initialize the device at start-up or reset on exit.
-
-	-noreset
-		Don't reset the device on exit.
-
-	-nolock
-		Don't attempt exclusive device use.`,
+` + serial.Options.Man(),
 	}
 }
 
-func (Command) Main(args ...string) error {
-	flag, args := flags.New(args, "-noinit", "-noreset", "-nolock")
-	parm, args := parms.New(args, "-baud", "-parity", "-databits",
-		"-stopbits")
-
-	if len(parm.ByName["-baud"]) == 0 {
-		parm.ByName["-baud"] = "115200"
-	}
-	baud, found := map[string]uint32{
-		"50":      syscall.B50,
-		"75":      syscall.B75,
-		"110":     syscall.B110,
-		"134":     syscall.B134,
-		"150":     syscall.B150,
-		"200":     syscall.B200,
-		"300":     syscall.B300,
-		"600":     syscall.B600,
-		"1200":    syscall.B1200,
-		"1800":    syscall.B1800,
-		"2400":    syscall.B2400,
-		"4800":    syscall.B4800,
-		"9600":    syscall.B9600,
-		"19200":   syscall.B19200,
-		"38400":   syscall.B38400,
-		"57600":   syscall.B57600,
-		"115200":  syscall.B115200,
-		"230400":  syscall.B230400,
-		"460800":  syscall.B460800,
-		"500000":  syscall.B500000,
-		"576000":  syscall.B576000,
-		"921600":  syscall.B921600,
-		"1000000": syscall.B1000000,
-		"1152000": syscall.B1152000,
-		"1500000": syscall.B1500000,
-		"2000000": syscall.B2000000,
-		"2500000": syscall.B2500000,
-		"3000000": syscall.B3000000,
-		"3500000": syscall.B3500000,
-		"4000000": syscall.B4000000,
-	}[parm.ByName["-baud"]]
-	if !found {
-		return fmt.Errorf("%s: invalid baud", parm.ByName["-baud"])
-	}
-
-	if len(parm.ByName["-parity"]) == 0 {
-		parm.ByName["-parity"] = "none"
-	}
-	parity, found := map[string]uint32{
-		"odd":  syscall.PARENB | syscall.PARODD,
-		"even": syscall.PARENB,
-		"none": 0,
-	}[parm.ByName["-parity"]]
-	if !found {
-		return fmt.Errorf("%s: invalid parity", parm.ByName["-parity"])
-	}
+func (Command) Options() options.Options { return serial.Options }
 
-	if len(parm.ByName["-databits"]) == 0 {
-		parm.ByName["-databits"] = "8"
-	}
-	databits, found := map[string]uint32{
-		"5": syscall.CS5,
-		"6": syscall.CS6,
-		"7": syscall.CS7,
-		"8": syscall.CS8,
-	}[parm.ByName["-databits"]]
-	if !found {
-		return fmt.Errorf("%s: invalid databits", parm.ByName["-databits"])
-	}
-
-	if len(parm.ByName["-stopbits"]) == 0 {
-		parm.ByName["-stopbits"] = "1"
-	}
-	stopbits, found := map[string]uint32{
-		"1": 0,
-		"2": syscall.CSTOPB,
-	}[parm.ByName["-stopbits"]]
-	if !found {
-		return fmt.Errorf("%s: invalid stopbits", parm.ByName["-stopbits"])
+func (Command) Main(args ...string) error {
+	opt, args, err := serial.Options.Parse(args)
+	if err != nil {
+		return err
 	}
 
 	if len(args) > 1 {
@@ -160,7 +67,7 @@ func (Command) Main(args ...string) error {
 		}
 		defer dev.Close()
 
-		if isatty.IsTerminal(dev.Fd()) && !flag.ByName["-nolock"] {
+		if isatty.IsTerminal(dev.Fd()) && !opt.Bool("-nolock") {
 			_, _, errno := syscall.Syscall(syscall.SYS_IOCTL,
 				uintptr(dev.Fd()),
 				uintptr(syscall.TIOCEXCL),
@@ -185,7 +92,7 @@ func (Command) Main(args ...string) error {
 				return fmt.Errorf("TCGETS: %s: %v", args[0], errno)
 			}
 			defer func() {
-				if !flag.ByName["-noinit"] && !flag.ByName["-noreset"] {
+				if !opt.Bool("-noinit") && !opt.Bool("-noreset") {
 					syscall.Syscall(syscall.SYS_IOCTL,
 						uintptr(dev.Fd()),
 						uintptr(syscall.TCSETS),
@@ -193,7 +100,7 @@ func (Command) Main(args ...string) error {
 				}
 			}()
 
-			if !flag.ByName["-noinit"] {
+			if !opt.Bool("-noinit") {
 				t := savedDev
 				t.Iflag &^= syscall.IGNBRK |
 					syscall.BRKINT |
@@ -209,7 +116,7 @@ func (Command) Main(args ...string) error {
 					syscall.ICANON |
 					syscall.ISIG |
 					syscall.IEXTEN
-				t.Cflag = baud | parity | databits | stopbits |
+				t.Cflag = serial.Cflag(opt) |
 					syscall.HUPCL |
 					syscall.CREAD |
 					syscall.CLOCAL
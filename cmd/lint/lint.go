@@ -0,0 +1,75 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package lint
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/lang"
+	"github.com/platinasystems/url"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "lint" }
+
+func (*Command) Usage() string { return "lint URL..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "check goes scripts",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Parse each script without running it and print its problems as
+	FILE:LINE: MESSAGE. These are syntax errors, blocks like if/fi,
+	while/done and function braces that aren't balanced, and commands
+	that aren't in this goes or defined by the script.
+
+	The exit status is 1 if any problem was found.
+
+EXAMPLES
+	goes lint /etc/goes/start /etc/goes/stop`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (c *Command) Main(args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("URL: missing")
+	}
+	var failed bool
+	for _, arg := range args {
+		diags, err := c.lint(arg)
+		if err != nil {
+			return err
+		}
+		for _, d := range diags {
+			fmt.Println(d)
+		}
+		failed = failed || len(diags) > 0
+	}
+	if failed {
+		return goes.StatusError(1)
+	}
+	return nil
+}
+
+func (c *Command) lint(fn string) ([]goes.Diagnostic, error) {
+	r, err := url.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return c.g.Lint(fn, r)
+}
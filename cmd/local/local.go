@@ -0,0 +1,60 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package local
+
+import (
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "local" }
+
+func (*Command) Usage() string { return "local NAME[=VALUE]..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "set function variables",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Set the named context variables to VALUE, or empty if not given,
+	until the current function or sourced script returns. Then, the
+	caller's values are restored.
+
+EXAMPLES
+	function up {
+		local dev=$1
+		ip link set $dev up
+	}`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	for _, arg := range args {
+		name, value := arg, ""
+		if eq := strings.Index(arg, "="); eq >= 0 {
+			name, value = arg[:eq], arg[eq+1:]
+		}
+		if err := c.g.Local(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
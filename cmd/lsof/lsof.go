@@ -34,20 +34,48 @@ func (Command) Man() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: `
 DESCRIPTION
-	Print open files.`,
+	Print open files.
+
+	-json	Print open files as JSON.`,
 	}
 }
 
-func (Command) Main(args ...string) error {
-	pidlist := []int{}
+// A File is the JSON object of an open file.
+type File struct {
+	Command string `json:"command"`
+	Pid     int    `json:"pid"`
+	FD      string `json:"fd"`
+	Name    string `json:"name"`
+}
 
+func (Command) Main(args ...string) error {
 	if len(args) > 0 {
 		return fmt.Errorf("%v: unexpected", args)
 	}
+	files, err := openFiles()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Printf("%9s %d %s %s\n", f.Command, f.Pid, f.FD, f.Name)
+	}
+	return nil
+}
+
+func (Command) JSON(args ...string) (interface{}, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%v: unexpected", args)
+	}
+	return openFiles()
+}
+
+func openFiles() ([]File, error) {
+	var files []File
+	pidlist := []int{}
 
 	fns, err := ioutil.ReadDir("/proc")
 	if err != nil {
-		return fmt.Errorf("Error reading /proc: %s", err)
+		return nil, fmt.Errorf("Error reading /proc: %s", err)
 	}
 
 	for _, fn := range fns {
@@ -59,20 +87,19 @@ func (Command) Main(args ...string) error {
 	}
 	sort.Ints(pidlist)
 	for _, pid := range pidlist {
-		pidStr := strconv.Itoa(pid)
-		f := "/proc/" + pidStr
-		showFile(pidStr, "cwd", f+"/cwd", nil)
-		showFile(pidStr, "txt", f+"/exe", nil)
-		showFile(pidStr, "rtd", f+"/root", nil)
-		showFileSorted(pidStr, f+"/fd")
-		showFileDir(pidStr, "map", f+"/map_files", false)
+		f := "/proc/" + strconv.Itoa(pid)
+		addFile(&files, pid, "cwd", f+"/cwd", nil)
+		addFile(&files, pid, "txt", f+"/exe", nil)
+		addFile(&files, pid, "rtd", f+"/root", nil)
+		addFileSorted(&files, pid, f+"/fd")
+		addFileDir(&files, pid, "map", f+"/map_files", false)
 	}
-	return nil
+	return files, nil
 }
 
-func showFile(pid, kind, link string, dups map[string]bool) (err error) {
+func addFile(files *[]File, pid int, kind, link string, dups map[string]bool) (err error) {
 	cmdline := ""
-	cl, err := ioutil.ReadFile("/proc/" + pid + "/cmdline")
+	cl, err := ioutil.ReadFile(fmt.Sprint("/proc/", pid, "/cmdline"))
 	if err == nil {
 		cls := strings.Split(string(cl), "\x00")
 		if len(cls) > 0 {
@@ -93,11 +120,16 @@ func showFile(pid, kind, link string, dups map[string]bool) (err error) {
 		}
 		dups[file] = true
 	}
-	fmt.Printf("%9s %s %s %s\n", cmdline, pid, kind, file)
+	*files = append(*files, File{
+		Command: cmdline,
+		Pid:     pid,
+		FD:      kind,
+		Name:    file,
+	})
 	return nil
 }
 
-func showFileSorted(pid, dir string) (err error) {
+func addFileSorted(files *[]File, pid int, dir string) (err error) {
 	fdlist := []int{}
 	fns, err := ioutil.ReadDir(dir)
 	if err != nil {
@@ -114,12 +146,12 @@ func showFileSorted(pid, dir string) (err error) {
 	sort.Ints(fdlist)
 	for _, fd := range fdlist {
 		fn := strconv.Itoa(fd)
-		showFile(pid, fn, dir+"/"+fn, nil)
+		addFile(files, pid, fn, dir+"/"+fn, nil)
 	}
 	return nil
 }
 
-func showFileDir(pid, kind, dir string, dups bool) (err error) {
+func addFileDir(files *[]File, pid int, kind, dir string, dups bool) (err error) {
 	var dupList map[string]bool
 	if !dups {
 		dupList = make(map[string]bool)
@@ -134,7 +166,7 @@ func showFileDir(pid, kind, dir string, dups bool) (err error) {
 		if k == "" {
 			k = fn.Name()
 		}
-		showFile(pid, k, dir+"/"+fn.Name(), dupList)
+		addFile(files, pid, k, dir+"/"+fn.Name(), dupList)
 	}
 	return nil
 }
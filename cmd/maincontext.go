@@ -0,0 +1,14 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package cmd
+
+import "context"
+
+// A MainContexter is a command that may be cancelled while it runs. Goes
+// runs its MainContext rather than Main with a context that's done with
+// ^C of the in-process command, its timeout, or the SIGTERM of a daemon.
+type MainContexter interface {
+	MainContext(context.Context, ...string) error
+}
@@ -0,0 +1,13 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package cmd
+
+import "github.com/platinasystems/goes/external/options"
+
+// An Optioner is a command with declared options. Goes completes its
+// arguments with those, unless it also has a Complete method.
+type Optioner interface {
+	Options() options.Options
+}
@@ -5,22 +5,33 @@
 package ping
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"syscall"
 	"time"
 
+	"github.com/platinasystems/goes/external/options"
 	"github.com/platinasystems/goes/lang"
 	"github.com/tatsushid/go-fastping"
 )
 
 type Command struct{}
 
+var opts = options.Options{
+	{Name: "-c", Type: options.Int, Arg: "COUNT", Default: "1",
+		Help: "Stop after COUNT requests"},
+	{Name: "-s", Type: options.Int, Arg: "SIZE", Default: "64",
+		Help: "Send SIZE bytes of data"},
+	{Name: "-W", Type: options.Duration, Arg: "TIMEOUT", Default: "1",
+		Help: "Wait TIMEOUT for each reply"},
+	{Name: "-I", Type: options.IP, Arg: "ADDRESS",
+		Help: "Send from the source ADDRESS"},
+}
+
 func (Command) String() string { return "ping" }
 
-func (Command) Usage() string {
-	return "ping DESTINATION"
-}
+func (Command) Usage() string { return opts.Usage("ping", "DESTINATION") }
 
 func (Command) Apropos() lang.Alt {
 	return lang.Alt{
@@ -32,19 +43,46 @@ func (Command) Man() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: `
 DESCRIPTION
-	Send ICMP ECHO_REQUEST to given host and print ECHO_REPLY.`,
+	Send ICMP ECHO_REQUEST to given host and print ECHO_REPLY.
+
+` + opts.Man(),
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (Command) Options() options.Options { return opts }
+
+func (c Command) Main(args ...string) error {
+	return c.MainContext(context.Background(), args...)
+}
+
+func (Command) MainContext(ctx context.Context, args ...string) error {
+	opt, args, err := opts.Parse(args)
+	if err != nil {
+		return err
+	}
 	if n := len(args); n == 0 {
 		return fmt.Errorf("DESTINATION: missing")
 	} else if n > 1 {
 		return fmt.Errorf("%v: unexpected", args[1:])
 	}
+	if opt.Int("-c") < 1 {
+		return fmt.Errorf("%d: invalid count", opt.Int("-c"))
+	}
+	if opt.Int("-s") < 8 {
+		return fmt.Errorf("%d: invalid size", opt.Int("-s"))
+	}
+	if opt.Duration("-W") <= 0 {
+		return fmt.Errorf("%v: invalid timeout", opt.Duration("-W"))
+	}
 	dest := args[0]
 	pinger := fastping.NewPinger()
-	pinger.Size = 64
+	pinger.Size = opt.Int("-s")
+	pinger.MaxRTT = opt.Duration("-W")
+	if ip := opt.IP("-I"); ip != nil {
+		if _, err = pinger.Source(ip.String()); err != nil {
+			return err
+		}
+	}
 	da, err := net.ResolveIPAddr("ip4:icmp", dest)
 	if err != nil {
 		return err
@@ -58,8 +96,13 @@ func (Command) Main(args ...string) error {
 	}
 	pinger.OnIdle = func() {}
 	fmt.Printf("PING %s (%s)\n", dest, da.String())
-	if rerr := pinger.Run(); err == nil {
-		err = rerr
+	for i := 0; i < opt.Int("-c"); i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if rerr := pinger.Run(); rerr != nil {
+			return rerr
+		}
 	}
 	return err
 }
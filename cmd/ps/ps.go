@@ -43,19 +43,36 @@ DESCRIPTION
 	The default list is limitted to processes on controlling TTY.
 
 	-e  Select all processes.
-	-f  Full format listing.`,
+	-f  Full format listing.
+	-json
+	    Print the selected processes as JSON.`,
 	}
 }
 
-func (Command) Main(args ...string) error {
-	var ttynr uint
+// A Process is the JSON output of ps for each selected process.
+type Process struct {
+	UID       string        `json:"uid"`
+	PID       int           `json:"pid"`
+	PPID      int           `json:"ppid"`
+	State     string        `json:"state"`
+	TTY       string        `json:"tty"`
+	StartTime time.Time     `json:"start_time"`
+	Time      time.Duration `json:"time_ns"`
+	Comm      string        `json:"comm"`
+	Cmdline   []string      `json:"cmdline,omitempty"`
+}
 
+func (Command) Main(args ...string) error {
 	flag, args := flags.New(args, "-e", "-f")
 	if len(args) > 0 {
 		return fmt.Errorf("%v: unexpected", args)
 	}
 
-	pid := os.Getpid()
+	l, err := processes(flag.ByName["-e"])
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
 	boy := time.Date(now.Year(), 1, 1, 12, 0, 0, 0, now.Location())
 	bod := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0,
@@ -70,20 +87,69 @@ func (Command) Main(args ...string) error {
 		return fmt.Sprintf("%2d:%02d", t.Hour(), t.Minute())
 	}
 
+	switch {
+	case flag.ByName["-f"]:
+		fmt.Println("UID        PID  PPID  C STIME TTY          TIME CMD")
+	default:
+		fmt.Println("  PID TTY          TIME CMD")
+	}
+
+	for _, p := range l {
+		switch {
+		case flag.ByName["-f"]:
+			cmdline := p.Comm
+			if len(p.Cmdline) > 0 {
+				cmdline = strings.Join(p.Cmdline, " ")
+			}
+			fmt.Printf("%-8s %5d %5d %2s %5s %-7s %9s %s\n",
+				p.UID,
+				p.PID,
+				p.PPID,
+				p.State,
+				p.TTY,
+				stime(p.StartTime),
+				p.Time,
+				cmdline)
+		default:
+			fmt.Printf("%5d %-7s %9s %s\n",
+				p.PID,
+				p.TTY,
+				p.Time,
+				p.Comm)
+		}
+	}
+	return nil
+}
+
+func (Command) JSON(args ...string) (interface{}, error) {
+	flag, args := flags.New(args, "-e", "-f")
+	if len(args) > 0 {
+		return nil, fmt.Errorf("%v: unexpected", args)
+	}
+	return processes(flag.ByName["-e"])
+}
+
+// processes returns those on the controlling tty or, with all, every one.
+func processes(all bool) ([]Process, error) {
+	var ttynr uint
+
+	pid := os.Getpid()
+
 	fns, err := filepath.Glob("/proc/[0-9]*/stat")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	l := make([]*ps, len(fns))
-	for i, fn := range fns {
-		l[i], err = newps(fn)
+	l := make([]*ps, 0, len(fns))
+	for _, fn := range fns {
+		p, err := newps(fn)
 		if err != nil {
-			return fmt.Errorf("%s: %v", fn, err)
+			return nil, fmt.Errorf("%s: %v", fn, err)
 		}
-		if l[i].stat.Pid == pid {
-			ttynr = l[i].stat.TtyNr
+		if p.stat.Pid == pid {
+			ttynr = p.stat.TtyNr
 		}
+		l = append(l, p)
 	}
 
 	sort.Slice(l, func(i, j int) bool {
@@ -96,51 +162,35 @@ func (Command) Main(args ...string) error {
 		return l[i].uid < l[j].uid
 	})
 
-	switch {
-	case flag.ByName["-f"]:
-		fmt.Println("UID        PID  PPID  C STIME TTY          TIME CMD")
-	default:
-		fmt.Println("  PID TTY          TIME CMD")
-	}
-
 	tty := make(tty)
 	uid := make(uid)
 
+	processes := make([]Process, 0, len(l))
 	for _, ps := range l {
-		if flag.ByName["-e"] || ps.stat.TtyNr == ttynr {
-			switch {
-			case flag.ByName["-f"]:
-				cmdline := ps.stat.Comm
-				fn := fmt.Sprintf("/proc/%d/cmdline",
-					ps.stat.Pid)
-				buf, err := ioutil.ReadFile(fn)
-				if err == nil && len(buf) > 0 {
-					for i, b := range buf {
-						if b == 0 {
-							buf[i] = ' '
-						}
-					}
-					cmdline = string(buf)
-				}
-				fmt.Printf("%-8s %5d %5d %2s %5s %-7s %9s %s\n",
-					uid.Name(ps.uid),
-					ps.stat.Pid,
-					ps.stat.Ppid,
-					ps.stat.State,
-					tty.Name(ps.stat.TtyNr),
-					stime(ps.stat.StartTime),
-					ps.stat.Utime,
-					cmdline)
-			default:
-				fmt.Printf("%5d %-7s %9s %s\n",
-					ps.stat.Pid,
-					tty.Name(ps.stat.TtyNr),
-					ps.stat.Utime,
-					ps.stat.Comm)
+		if !all && ps.stat.TtyNr != ttynr {
+			continue
+		}
+		p := Process{
+			UID:       uid.Name(ps.uid),
+			PID:       ps.stat.Pid,
+			PPID:      ps.stat.Ppid,
+			State:     ps.stat.State,
+			TTY:       tty.Name(ps.stat.TtyNr),
+			StartTime: ps.stat.StartTime,
+			Time:      ps.stat.Utime,
+			Comm:      ps.stat.Comm,
+		}
+		fn := fmt.Sprintf("/proc/%d/cmdline", ps.stat.Pid)
+		buf, err := ioutil.ReadFile(fn)
+		if err == nil && len(buf) > 0 {
+			buf = bytes.TrimRight(buf, "\x00")
+			for _, arg := range bytes.Split(buf, []byte{0}) {
+				p.Cmdline = append(p.Cmdline, string(arg))
 			}
 		}
+		processes = append(processes, p)
 	}
-	return nil
+	return processes, nil
 }
 
 type ps struct {
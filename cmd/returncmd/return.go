@@ -0,0 +1,61 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package returncmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct{}
+
+func (Command) String() string { return "return" }
+
+func (Command) Usage() string { return "return [N]" }
+
+func (Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "return from a function or sourced script",
+	}
+}
+
+func (Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	End the enclosing function or sourced script with the exit status
+	N or, if not given, that of the last command.`,
+	}
+}
+
+func (c Command) Block(g *goes.Goes, ls shellutils.List) (*shellutils.List, func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
+	cl := ls.Cmds[0]
+	if len(cl.Cmds) > 2 {
+		return nil, nil, fmt.Errorf("%s: too many arguments", c)
+	}
+	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		n := goes.ExitStatus(g.Status)
+		if _, args := cl.SliceSubst(g.Getenv, g.Cmdsubst); len(args) > 1 {
+			var err error
+			n, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("%s: %s: numeric argument required",
+					c, args[1])
+			}
+		}
+		return &goes.ReturnError{Status: n & 0xff}
+	}
+	return &ls, runfun, nil
+}
+
+func (Command) Main(args ...string) error {
+	return errors.New("internal error")
+}
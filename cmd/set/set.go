@@ -0,0 +1,134 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package set
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "set" }
+
+func (*Command) Usage() string {
+	return "set [-+ex] [-+o OPTION]..."
+}
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "set or unset shell options",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Set shell options with '-' or unset them with '+'. Without arguments,
+	print the context variables; with just '-o' or '+o', print the
+	options.
+
+OPTIONS
+	-e, -o errexit
+		Exit a script on the failure of any command other than the
+		condition of if, while or until, or a pipeline followed by
+		'&&' or '||'.
+
+	-o pipefail
+		The status of a pipeline is that of its last failing stage
+		rather than its last stage.
+
+	-x, -o xtrace
+		Print each command before it's executed.
+
+EXAMPLES
+	set -e
+	ip link set dev eth0 up
+	set +e -o pipefail`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	if len(args) == 0 {
+		keys := make([]string, 0, len(c.g.EnvMap))
+		for k := range c.g.EnvMap {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("%s=%s\n", k, c.g.EnvMap[k])
+		}
+		return nil
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+			return fmt.Errorf("%s: unexpected", arg)
+		}
+		on := arg[0] == '-'
+		for _, opt := range arg[1:] {
+			switch opt {
+			case 'e':
+				c.option("errexit", on)
+			case 'x':
+				c.option("xtrace", on)
+			case 'o':
+				if i++; i == len(args) {
+					c.show()
+					return nil
+				}
+				if err := c.option(args[i], on); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%s%c: unknown option",
+					arg[:1], opt)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Command) option(name string, on bool) error {
+	switch name {
+	case "errexit":
+		c.g.ErrExit = on
+	case "pipefail":
+		c.g.PipeFail = on
+	case "xtrace":
+		if on && c.g.Verbosity < goes.VerboseVerify {
+			c.g.Verbosity = goes.VerboseVerify
+		} else if !on && c.g.Verbosity == goes.VerboseVerify {
+			c.g.Verbosity = goes.VerboseQuiet
+		}
+	default:
+		return fmt.Errorf("%s: unknown option", name)
+	}
+	return nil
+}
+
+func (c *Command) show() {
+	onoff := func(on bool) string {
+		if on {
+			return "on"
+		}
+		return "off"
+	}
+	fmt.Printf("errexit\t\t%s\n", onoff(c.g.ErrExit))
+	fmt.Printf("pipefail\t%s\n", onoff(c.g.PipeFail))
+	fmt.Printf("xtrace\t\t%s\n",
+		onoff(c.g.Verbosity >= goes.VerboseVerify))
+}
@@ -0,0 +1,65 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shift
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "shift" }
+
+func (*Command) Usage() string { return "shift [N]" }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "shift positional parameters",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Discard the first, or first N, positional parameters of the current
+	function or sourced script so that $1 is the next.
+
+EXAMPLES
+	function usage {
+		echo usage: $1 >&2
+		shift
+		for arg in $@; do echo "	$arg" >&2; done
+	}`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	n := 1
+	switch len(args) {
+	case 0:
+	case 1:
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("%s: numeric argument required",
+				args[0])
+		}
+	default:
+		return fmt.Errorf("%v: unexpected", args[1:])
+	}
+	return c.g.Shift(n)
+}
@@ -5,6 +5,7 @@
 package sleep
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -32,7 +33,11 @@ DESCRIPTION
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (c Command) Main(args ...string) error {
+	return c.MainContext(context.Background(), args...)
+}
+
+func (Command) MainContext(ctx context.Context, args ...string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("SECONDS: missing")
 	}
@@ -42,6 +47,12 @@ func (Command) Main(args ...string) error {
 		return fmt.Errorf("%s: %v", args[0], err)
 	}
 
-	time.Sleep(time.Second * time.Duration(t))
-	return nil
+	timer := time.NewTimer(time.Second * time.Duration(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
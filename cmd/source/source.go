@@ -20,7 +20,7 @@ type Command struct {
 func (*Command) String() string { return "source" }
 
 func (*Command) Usage() string {
-	return "source [-x] FILE"
+	return "source [-x] FILE [ARG]..."
 }
 
 func (*Command) Apropos() lang.Alt {
@@ -33,7 +33,11 @@ func (*Command) Man() lang.Alt {
 	return lang.Alt{
 		lang.EnUS: `
 DESCRIPTION
-	This is equivalent to 'cli [-x] URL'.`,
+	This is equivalent to 'cli [-x] URL [ARG]...' but run in the current
+	context, so the script may set variables and define functions.
+
+	The ARGs are available to the script as $1, $2, etc.; without any,
+	the script has the positional parameters of its caller.`,
 	}
 }
 
@@ -46,14 +50,14 @@ func (c *Command) Main(args ...string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("FILE: missing")
 	}
-	if len(args) > 1 {
-		return fmt.Errorf("%v: unexpected", args[1:])
-	}
 	if flag.ByName["-x"] {
-		args = []string{"cli", "-x", args[0]}
+		args = append([]string{"cli", "-x"}, args...)
 	} else {
-		args = []string{"cli", args[0]}
+		args = append([]string{"cli"}, args...)
 	}
-	c.g.Catline = nil // Reset the input source
+	// Reset the input source until the script is done
+	catline := c.g.Catline
+	defer func() { c.g.Catline = catline }()
+	c.g.Catline = nil
 	return c.g.Main(args...)
 }
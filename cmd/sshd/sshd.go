@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"os"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/gliderlabs/ssh"
@@ -44,6 +45,38 @@ func (*Command) Apropos() lang.Alt {
 	}
 }
 
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Serve a cli, or run the given command, for each session with a key of
+	/etc/goes/sshd/authorized_keys.
+
+ROLES
+	With /etc/goes/sshd/roles, each session has the role of its key
+	fingerprint or user, or the default role. Those without a role are
+	refused. The commands of a session, including those given to ssh,
+	are then limited to those that the role allows, e.g.
+
+	role operator
+		allow ip * show
+		allow ip route show
+		allow eeprom
+		allow > /tmp/*
+		deny *
+	role admin
+		allow *
+	user alice admin
+	key SHA256:2dJf0nqV9... operator
+	default operator
+
+	Each pattern matches the leading words of a command. The first match
+	allows or denies the command; those without a match are denied. The
+	pattern "> URL" matches output redirections. Subshells and background
+	jobs run "cli".`,
+	}
+}
+
 func (c *Command) Goes(g *goes.Goes) { c.g = g }
 
 func (*Command) Kind() cmd.Kind { return cmd.Daemon }
@@ -81,14 +114,45 @@ func (c *Command) Main(args ...string) (err error) {
 	}
 
 	srv.Handle(func(s ssh.Session) {
+		id := goes.NewAuditSession()
+		session := []string{
+			goes.AuditSessionEnv + "=" + id,
+			goes.AuditUserEnv + "=" + s.User(),
+			goes.AuditRemoteEnv + "=" + s.RemoteAddr().String(),
+		}
 		cmdline := s.Command()
+		role, _ := s.Context().Value(roleKey{}).(string)
+		if len(role) > 0 {
+			session = append(session, goes.AuthRoleEnv+"="+role)
+			// so that the cli authorizes each command
+			if len(cmdline) > 0 {
+				cmdline = []string{"cli", "-c", s.RawCommand()}
+			}
+		}
 		if len(cmdline) == 0 {
 			cmdline = []string{"cli"}
 		}
 		cmd := prog.Command(cmdline...)
+		// a cli audits each of its commands; others are audited here
+		var waitErr error
+		start := time.Now()
+		defer func() {
+			if cmdline[0] != "cli" && cmd.ProcessState != nil {
+				goes.AuditRecord{
+					Time:     start,
+					Session:  id,
+					User:     s.User(),
+					Remote:   s.RemoteAddr().String(),
+					Args:     cmdline,
+					Status:   goes.ExitStatus(waitErr),
+					Duration: time.Since(start),
+				}.Log()
+			}
+		}()
 		ptyReq, winCh, isPty := s.Pty()
 		if isPty {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("TERM=%s", ptyReq.Term))
+			cmd.Env = append(session,
+				fmt.Sprintf("TERM=%s", ptyReq.Term))
 			f, err := pty.Start(cmd)
 			if err != nil {
 				panic(err)
@@ -111,7 +175,9 @@ func (c *Command) Main(args ...string) (err error) {
 				io.Copy(f, s) // stdin
 			}()
 			io.Copy(s, f) // stdout
+			waitErr = cmd.Wait()
 		} else {
+			cmd.Env = append(os.Environ(), session...)
 			cmd.Stdin = s // blocks exit - do not know why
 			cmd.Stdout = s
 			cmd.Stderr = s.Stderr()
@@ -121,6 +187,7 @@ func (c *Command) Main(args ...string) (err error) {
 				s.Exit(1)
 			}
 			err = cmd.Wait()
+			waitErr = err
 			log.Print("sshd wait exited ", err)
 			if err == nil {
 				s.Exit(0)
@@ -131,32 +198,7 @@ func (c *Command) Main(args ...string) (err error) {
 	})
 
 	err = srv.SetOption(ssh.PublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
-		// check permissions on authorized_keys
-		authKeys, err := ioutil.ReadFile("/etc/goes/sshd/authorized_keys")
-		if err != nil {
-			if !os.IsNotExist(err) {
-				fmt.Printf("Error reading authorized keys: %s\n", err)
-				return c.FailSafe
-			}
-			authKeys, err = ioutil.ReadFile("/etc/goes/sshd/authorized_keys.default")
-			if err != nil {
-				fmt.Printf("Error reading authorized keys.default: %s\n", err)
-				return c.FailSafe
-			}
-		}
-
-		for len(authKeys) > 0 {
-			authKey, _, _, rest, err := gossh.ParseAuthorizedKey(authKeys)
-			if err != nil {
-				fmt.Printf("Error parsing authorized_keys: %s\n", err)
-				return false
-			}
-			if ssh.KeysEqual(authKey, key) {
-				return true
-			}
-			authKeys = rest
-		}
-		return false // No matching key found
+		return c.isAuthorized(key) && assignRole(ctx, key)
 	}))
 
 	err = srv.SetOption(ssh.HostKeyFile("/etc/goes/sshd/id_rsa"))
@@ -178,3 +220,53 @@ func (c *Command) Main(args ...string) (err error) {
 		}
 	}
 }
+
+func (c *Command) isAuthorized(key ssh.PublicKey) bool {
+	// check permissions on authorized_keys
+	authKeys, err := ioutil.ReadFile("/etc/goes/sshd/authorized_keys")
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Error reading authorized keys: %s\n", err)
+			return c.FailSafe
+		}
+		authKeys, err = ioutil.ReadFile("/etc/goes/sshd/authorized_keys.default")
+		if err != nil {
+			fmt.Printf("Error reading authorized keys.default: %s\n", err)
+			return c.FailSafe
+		}
+	}
+
+	for len(authKeys) > 0 {
+		authKey, _, _, rest, err := gossh.ParseAuthorizedKey(authKeys)
+		if err != nil {
+			fmt.Printf("Error parsing authorized_keys: %s\n", err)
+			return false
+		}
+		if ssh.KeysEqual(authKey, key) {
+			return true
+		}
+		authKeys = rest
+	}
+	return false // No matching key found
+}
+
+type roleKey struct{}
+
+// assignRole gives the session the role of its key or user in the
+// goes.RolesFile, if any. Without such a file, all have full access.
+func assignRole(ctx ssh.Context, key ssh.PublicKey) bool {
+	roles, err := goes.LoadRoles(goes.RolesFile)
+	if os.IsNotExist(err) {
+		return true
+	}
+	if err != nil {
+		fmt.Printf("Error reading roles: %s\n", err)
+		return false
+	}
+	role, found := roles.Of(ctx.User(), gossh.FingerprintSHA256(key))
+	if !found {
+		return false
+	}
+	ctx.SetValue(roleKey{}, role)
+	return true
+}
@@ -16,6 +16,7 @@ import (
 	"syscall"
 
 	"github.com/creack/pty"
+	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/internal/telnet/command"
 	"github.com/platinasystems/goes/internal/telnet/option"
@@ -65,6 +66,10 @@ func (Command) Main(args ...string) error {
 				Env: []string{
 					"PATH=/usr/bin:/bin",
 					"TERM=xterm",
+					goes.AuditSessionEnv + "=" +
+						goes.NewAuditSession(),
+					goes.AuditRemoteEnv + "=" +
+						conn.RemoteAddr().String(),
 				},
 				Files: []*os.File{
 					tty,
@@ -0,0 +1,125 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package timeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/goes/lang"
+)
+
+// Status is that of a command that didn't finish in time.
+const Status = 124
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "timeout" }
+
+func (*Command) Usage() string { return "timeout DURATION COMMAND [ARG]..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "run a command with a time limit",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Run the command, function or alias and stop it if still running
+	after DURATION. This is a number of seconds or a duration with
+	units like 1m30s or 500ms.
+
+	A forked command is sent SIGTERM. The context of an in-process
+	command is cancelled, which stops it if it waits on this context,
+	like sleep, hwait, ping and wget.
+
+	The exit status is 124 if the command timed out and otherwise that
+	of the command.
+
+EXAMPLES
+	timeout 10 hwait platina redis.ready true 60
+	timeout 1m ping 10.0.0.1`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c *Command) Main(args ...string) error {
+	return c.MainContext(context.Background(), args...)
+}
+
+func (c *Command) MainContext(ctx context.Context, args ...string) error {
+	switch len(args) {
+	case 0:
+		return fmt.Errorf("DURATION: missing")
+	case 1:
+		return fmt.Errorf("COMMAND: missing")
+	}
+	d, err := duration(args[0])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	var (
+		cl      shellutils.Cmdline
+		closers []io.Closer
+	)
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+	for _, arg := range args[1:] {
+		cl.Cmds = append(cl.Cmds, shellutils.Word{
+			Tokens: []shellutils.Token{
+				{V: arg, T: shellutils.TokenLiteral},
+			},
+		})
+	}
+	err = c.g.WithContext(ctx, func() error {
+		f, err := c.g.ProcessCommand(cl, &closers)
+		if err != nil {
+			return err
+		}
+		return f(os.Stdin, os.Stdout, os.Stderr)
+	})
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return goes.StatusError(Status)
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case err == nil && c.g.Status != nil:
+		// a forked command's failure has been reported
+		err = goes.StatusError(goes.ExitStatus(c.g.Status))
+	}
+	return err
+}
+
+func duration(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid duration", s)
+	}
+	return d, nil
+}
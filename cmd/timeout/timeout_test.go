@@ -0,0 +1,64 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package timeout_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/cmd/timeout"
+	"github.com/platinasystems/goes/lang"
+)
+
+// block is an in-process command that runs until its context is done or,
+// failing that, for 10 seconds.
+type block struct{}
+
+func (block) String() string { return "block" }
+
+func (block) Usage() string { return "block" }
+
+func (block) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "wait for cancellation",
+	}
+}
+
+func (block) Kind() cmd.Kind { return cmd.DontFork }
+
+func (c block) Main(args ...string) error {
+	return c.MainContext(context.Background(), args...)
+}
+
+func (block) MainContext(ctx context.Context, args ...string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(10 * time.Second):
+		return nil
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	g := &goes.Goes{
+		NAME: "goes",
+		ByName: map[string]cmd.Cmd{
+			"block":   block{},
+			"timeout": &timeout.Command{},
+		},
+	}
+	start := time.Now()
+	err := g.Main("timeout", "100ms", "block")
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatal("not cancelled after", d)
+	}
+	if goes.ExitStatus(err) != timeout.Status {
+		t.Errorf("%v: exit status %d, want %d", err,
+			goes.ExitStatus(err), timeout.Status)
+	}
+}
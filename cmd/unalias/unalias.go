@@ -0,0 +1,58 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package unalias
+
+import (
+	"fmt"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/external/flags"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "unalias" }
+
+func (*Command) Usage() string { return "unalias [-a] NAME..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "remove command aliases",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Remove the alias of each NAME or, with '-a', all aliases.`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	flag, args := flags.New(args, "-a")
+	if flag.ByName["-a"] {
+		c.g.AliasMap = nil
+		return nil
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("NAME: missing")
+	}
+	for _, name := range args {
+		if _, found := c.g.AliasMap[name]; !found {
+			return fmt.Errorf("%s: not found", name)
+		}
+		delete(c.g.AliasMap, name)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package wait
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+type Command struct {
+	g *goes.Goes
+}
+
+func (*Command) String() string { return "wait" }
+
+func (*Command) Usage() string { return "wait [%JOB | PID]..." }
+
+func (*Command) Apropos() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: "wait for background jobs to finish",
+	}
+}
+
+func (*Command) Man() lang.Alt {
+	return lang.Alt{
+		lang.EnUS: `
+DESCRIPTION
+	Wait for each given job, or all jobs, to finish. The status is that
+	of the last job given, or success if none.
+
+EXAMPLES
+	ping -c 10 10.0.0.1 > ping.log &
+	wait $!`,
+	}
+}
+
+func (c *Command) Goes(g *goes.Goes) { c.g = g }
+
+func (*Command) Kind() cmd.Kind { return cmd.DontFork | cmd.CantPipe }
+
+func (c *Command) Main(args ...string) error {
+	if len(args) == 0 {
+		for _, j := range c.g.Jobs() {
+			if err := c.g.WaitJob(j); goes.IsUnwinding(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	var err error
+	for _, arg := range args {
+		j, terr := c.find(arg)
+		if terr != nil {
+			return terr
+		}
+		err = c.g.WaitJob(j)
+		if goes.IsUnwinding(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (c *Command) find(arg string) (*goes.Job, error) {
+	if strings.HasPrefix(arg, "%") {
+		return c.g.FindJob(arg)
+	}
+	pid, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range c.g.Jobs() {
+		if j.Pgid == pid {
+			return j, nil
+		}
+	}
+	return nil, fmt.Errorf("%d: not a child of this cli", pid)
+}
@@ -10,12 +10,11 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/platinasystems/goes"
 	"github.com/platinasystems/goes/cmd"
-	"github.com/platinasystems/goes/external/parms"
+	"github.com/platinasystems/goes/external/options"
 	"github.com/platinasystems/goes/lang"
 	"github.com/platinasystems/gpio"
 )
@@ -26,7 +25,14 @@ type Command struct {
 
 func (*Command) String() string { return "watchdog" }
 
-func (*Command) Usage() string { return "watchdog [OPTION]... [DEVICE]" }
+var opts = options.Options{
+	{Name: "-T", Type: options.Duration, Arg: "TIMEOUT", Default: "60",
+		Help: "Reboot after TIMEOUT without a watchdog write"},
+	{Name: "-t", Type: options.Duration, Arg: "FREQUENCY", Default: "30",
+		Help: "Write every FREQUENCY"},
+}
+
+func (*Command) Usage() string { return opts.Usage("watchdog", "[DEVICE]") }
 
 func (*Command) Apropos() lang.Alt {
 	return lang.Alt{
@@ -39,35 +45,26 @@ func (*Command) Man() lang.Alt {
 		lang.EnUS: `
 DESCRIPTION
 	Periodically write to the watchdog device (default /dev/watchdog).
+	The TIMEOUT and FREQUENCY are seconds, e.g. 30, or durations like
+	1m30s.
 
-OPTIONS
-	-T TIMEOUT	Reboot after TIMEOUT seconds without a watchdog write
-			(default 60)
-	-t FREQUENCY	Write frequency in seconds
-			(default 30)`,
+` + opts.Man(),
 	}
 }
 
+func (*Command) Options() options.Options { return opts }
+
 func (*Command) Kind() cmd.Kind { return cmd.Daemon }
 
 func (c *Command) Main(args ...string) error {
-	parm, args := parms.New(args, "-T", "-t")
-	for k, v := range map[string]string{
-		"-T": "60",
-		"-t": "30",
-	} {
-		if len(parm.ByName[k]) == 0 {
-			parm.ByName[k] = v
-		}
-	}
-
-	period, err := strconv.ParseInt(parm.ByName["-t"], 0, 0)
+	opt, args, err := opts.Parse(args)
 	if err != nil {
-		return fmt.Errorf("%s: invalid Period: ", err)
-	} else if period <= 0 {
-		return fmt.Errorf("%v: invalid period", period)
+		return err
+	}
+	freq := opt.Duration("-t")
+	if freq <= 0 {
+		return fmt.Errorf("%v: invalid frequency", freq)
 	}
-	freq := time.Duration(period) * time.Second
 
 	fn := "/dev/watchdog"
 	if n := len(args); n > 0 {
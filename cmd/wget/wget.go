@@ -5,6 +5,7 @@
 package wget
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/cavaliercoder/grab"
@@ -24,7 +25,11 @@ func (Command) Apropos() lang.Alt {
 	}
 }
 
-func (Command) Main(args ...string) error {
+func (c Command) Main(args ...string) error {
+	return c.MainContext(context.Background(), args...)
+}
+
+func (Command) MainContext(ctx context.Context, args ...string) error {
 	// validate command args
 	if len(args) < 1 {
 		return fmt.Errorf("URL: missing")
@@ -36,10 +41,14 @@ func (Command) Main(args ...string) error {
 		if err != nil {
 			return err
 		}
+		req.HTTPRequest = req.HTTPRequest.WithContext(ctx)
 		reqs = append(reqs, req)
 	}
 
 	successes, err := url.FetchReqs(0, reqs)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if successes == 0 && err != nil {
 		return err
 	}
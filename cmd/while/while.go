@@ -6,7 +6,6 @@ package whilecmd
 
 import (
 	"errors"
-	"fmt"
 	"io"
 
 	"github.com/platinasystems/goes"
@@ -111,20 +110,25 @@ func runList(pipeline []func(stdin io.Reader, stdout io.Writer, stderr io.Writer
 func (c Command) makeBlockFunc(g *goes.Goes, whileList, doList []func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
 	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
 		for {
-			err := runList(whileList, stdin, stdout, stderr)
-			if (err == nil && g.Status == nil) != c.IsUntil {
-				err = runList(doList, stdin, stdout, stderr)
-				if err != nil {
-					fmt.Fprintln(stderr, err)
-				}
-				if g.Status != nil {
-					if g.Status.Error() == "signal: interrupt" {
-						return g.Status
-					}
-				}
-			} else {
+			err := g.Condition(func() error {
+				return runList(whileList, stdin, stdout, stderr)
+			})
+			if err != nil {
 				return err
 			}
+			if (g.Status == nil) == c.IsUntil {
+				g.Status = nil
+				return nil
+			}
+			stop, err := g.Loop(runList(doList, stdin, stdout, stderr))
+			if stop {
+				return err
+			}
+			if g.Status != nil {
+				if g.Status.Error() == "signal: interrupt" {
+					return g.Status
+				}
+			}
 		}
 	}
 	return runfun, nil
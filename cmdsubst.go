@@ -0,0 +1,128 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// lines is a Catline that feeds a script one line per Read.
+type lines []string
+
+func (l *lines) Read(p []byte) (n int, err error) {
+	if len(*l) == 0 {
+		return 0, io.EOF
+	}
+	s := (*l)[0]
+	*l = (*l)[1:]
+	n = copy(p, s)
+	if len(s) > len(p) {
+		err = errors.New("input too long")
+	}
+	return
+}
+
+func (l *lines) Write(p []byte) (n int, err error) {
+	return len(p), nil
+}
+
+// Getenv returns the value of the named variable in the goes context or,
+// if not set there, the process environment. The special "?" variable is
+// the exit status of the last command and "!" is the process id of the last
+// background job. Numbered variables are the positional parameters of the
+// current function or sourced script, "#" is their count, and "@" or "*"
+// are all of them separated by spaces.
+func (g *Goes) Getenv(k string) string {
+	switch k {
+	case "#":
+		return strconv.Itoa(len(g.Args()))
+	case "@", "*":
+		return strings.Join(g.Args(), " ")
+	case "?":
+		return strconv.Itoa(ExitStatus(g.Status))
+	case "!":
+		if g.jobs.last == 0 {
+			return ""
+		}
+		return strconv.Itoa(g.jobs.last)
+	}
+	if n, err := strconv.Atoi(k); err == nil && n > 0 {
+		if args := g.Args(); n <= len(args) {
+			return args[n-1]
+		}
+		return ""
+	}
+	if v, def := g.EnvMap[k]; def {
+		return v
+	}
+	return os.Getenv(k)
+}
+
+// Cmdsubst runs the given command text in the current context and returns
+// its standard output less trailing newlines. This is the $(COMMAND) and
+// `COMMAND` expansion of Cmdline.SliceSubst.
+//
+// The output of the commands is a pipe that is drained for the result;
+// in-process commands have it as their os.Stdout while they run, see stdio.
+func (g *Goes) Cmdsubst(s string) string {
+	stdin, _, stderr := g.std()
+	r, w, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ""
+	}
+	buf := new(bytes.Buffer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(buf, r)
+		r.Close()
+	}()
+
+	script := lines(strings.Split(s, "\n"))
+	catline := g.Catline
+	g.Catline = &script
+	err = g.runScript(stdin, w, stderr)
+	g.Catline = catline
+	w.Close()
+	<-done
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+	}
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// runScript parses and runs each command list read from Catline until EOF.
+func (g *Goes) runScript(stdin io.Reader, stdout, stderr io.Writer) error {
+	for {
+		ls, err := shellutils.Parse("", g.Catline)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		for len(ls.Cmds) != 0 {
+			newls, _, runner, err := g.ProcessList(*ls)
+			if err == nil {
+				err = runner(stdin, stdout, stderr)
+			}
+			if err != nil {
+				g.Status = err
+				return err
+			}
+			ls = newls
+		}
+	}
+}
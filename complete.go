@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/platinasystems/goes/cmd"
 )
 
 type completer interface {
@@ -17,11 +19,16 @@ type completer interface {
 
 func (g *Goes) Complete(args ...string) (completions []string) {
 	n := len(args)
+	if n <= 1 {
+		g.loadPlugins()
+	}
 	if n == 0 || len(args[0]) == 0 {
 		completions = g.Names()
-	} else if v, found := g.ByName[args[0]]; found {
+	} else if v, found := g.lookup(args[0]); found {
 		if method, found := v.(completer); found {
 			completions = method.Complete(args[1:]...)
+		} else if method, found := v.(cmd.Optioner); found {
+			completions = method.Options().Complete(args[1:]...)
 		} else {
 			completions, _ = filepath.Glob(args[n-1] + "*")
 		}
@@ -0,0 +1,91 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"context"
+	"errors"
+	"syscall"
+)
+
+// Context returns the context of the running command, which is that of
+// the parent goes if not set by MainContext.
+func (g *Goes) Context() context.Context {
+	for p := g; p != nil; p = p.parent {
+		if p.ctx != nil {
+			return p.ctx
+		}
+	}
+	return context.Background()
+}
+
+// MainContext is Main with the given context for any cmd.MainContexter and
+// the commands of its lists.
+func (g *Goes) MainContext(ctx context.Context, args ...string) error {
+	return g.WithContext(ctx, func() error {
+		return g.Main(args...)
+	})
+}
+
+// WithContext runs f with the given context for the commands it processes.
+func (g *Goes) WithContext(ctx context.Context, f func() error) error {
+	saved := g.ctx
+	g.ctx = ctx
+	defer func() { g.ctx = saved }()
+	return f()
+}
+
+// foreground runs an in-process command with a context that's cancelled
+// by ^C, which is then relayed to the cli as it is for forked commands.
+func (g *Goes) foreground(args []string) error {
+	parent := g.Context()
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	go func() {
+		select {
+		case sig := <-IntSig:
+			cancel()
+			select {
+			case IntSig <- sig:
+			default:
+			}
+		case <-ctx.Done():
+		}
+	}()
+	err := g.MainContext(ctx, args...)
+	if errors.Is(err, context.Canceled) && parent.Err() == nil {
+		err = nil
+	}
+	return err
+}
+
+// killOnDone terminates the forked child if the context is done first.
+func (g *Goes) killOnDone(c *child) {
+	done := g.Context().Done()
+	if done == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-done:
+			c.x.Process.Signal(syscall.SIGTERM)
+		case <-c.done:
+		}
+	}()
+}
+
+// cancelOnTerm returns a context of the daemon that's cancelled with the
+// close of Stop on SIGTERM.
+func cancelOnTerm(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-Stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -27,6 +27,9 @@ const DevLog = "/dev/log"
 const PriorityMask = syslog.Priority(7)
 const FacilityMask = ^PriorityMask
 
+// LOG_AUDIT is the "log audit" facility of RFC 5424 that log/syslog lacks.
+const LOG_AUDIT = syslog.Priority(13 << 3)
+
 type Seq uint64
 type Stamp uint64
 type Delta uint64
@@ -110,6 +113,7 @@ var FacilityByName = map[string]syslog.Priority{
 	"cron":   syslog.LOG_CRON,
 	"priv":   syslog.LOG_AUTHPRIV,
 	"ftp":    syslog.LOG_FTP,
+	"audit":  LOG_AUDIT,
 	"local0": syslog.LOG_LOCAL0,
 	"local1": syslog.LOG_LOCAL1,
 	"local2": syslog.LOG_LOCAL2,
@@ -133,6 +137,7 @@ var LogFacilityByValue = map[syslog.Priority]string{
 	syslog.LOG_CRON:     "cron",
 	syslog.LOG_AUTHPRIV: "priv",
 	syslog.LOG_FTP:      "ftp",
+	LOG_AUDIT:           "audit",
 	syslog.LOG_LOCAL0:   "local0",
 	syslog.LOG_LOCAL1:   "local1",
 	syslog.LOG_LOCAL2:   "local2",
@@ -0,0 +1,269 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package options parses typed command options from their declaration,
+// which also provides the command's usage, man OPTIONS and completions.
+//
+//	var opts = options.Options{
+//		{Name: "-v", Help: "print more"},
+//		{Name: "-t", Type: options.Duration, Arg: "TIMEOUT",
+//			Default: "30", Help: "give up after TIMEOUT"},
+//		{Name: "-i", Aliases: []string{"-dev"}, Type: options.Ifname,
+//			Help: "the interface"},
+//	}
+//
+//	opt, args, err := opts.Parse(args)
+//	if err != nil {
+//		return err
+//	}
+//	timeout := opt.Duration("-t")
+//
+// Like package flags, single letter boolean options may be combined, e.g.
+// -vx; like package parms, the others are given as -NAME VALUE or
+// -NAME=VALUE.
+package options
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/platinasystems/goes/external/flags"
+	"github.com/platinasystems/goes/external/parms"
+)
+
+type Options []Option
+
+type Option struct {
+	Name    string
+	Aliases []string
+	// Type of the option value; nil is Bool
+	Type Type
+	// Arg is the value placeholder of usage and man, by default the
+	// upper case name, e.g. BAUD of -baud
+	Arg     string
+	Default string
+	Help    string
+	// Complete overrides the completion of the Type
+	Complete func(prefix string) []string
+}
+
+// Values of the parsed options by name.
+type Values map[string]interface{}
+
+// Parse returns the value of each given option, or its default, and the
+// remaining arguments.
+func (opts Options) Parse(args []string) (Values, []string, error) {
+	var bools, others []interface{}
+	for _, o := range opts {
+		names := append([]string{o.Name}, o.Aliases...)
+		if o.IsBool() {
+			bools = append(bools, names)
+		} else {
+			others = append(others, names)
+		}
+	}
+	flag, args := flags.New(args, bools...)
+	parm, args := parms.New(args, others...)
+	vals := make(Values)
+	for _, o := range opts {
+		if o.IsBool() {
+			if flag.ByName[o.Name] {
+				vals[o.Name] = true
+			}
+			continue
+		}
+		s := parm.ByName[o.Name]
+		if len(s) == 0 {
+			s = o.Default
+		}
+		if len(s) == 0 {
+			continue
+		}
+		v, err := o.Type.Parse(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: invalid %s", s,
+				strings.ToLower(o.ArgName()))
+		}
+		vals[o.Name] = v
+	}
+	return vals, args, nil
+}
+
+// Usage returns the command name followed by its options and operands,
+// e.g. "dmesg [-CcDd] [-F FILE] [-n LEVEL]"
+func (opts Options) Usage(name string, operands ...string) string {
+	words := []string{name}
+	letters := ""
+	for _, o := range opts {
+		if o.IsBool() && len(o.Name) == 2 {
+			letters += o.Name[1:]
+		}
+	}
+	if len(letters) > 0 {
+		words = append(words, "[-"+letters+"]")
+	}
+	for _, o := range opts {
+		switch {
+		case !o.IsBool():
+			words = append(words, "["+o.Name+" "+o.ArgName()+"]")
+		case len(o.Name) > 2:
+			words = append(words, "["+o.Name+"]")
+		}
+	}
+	return strings.Join(append(words, operands...), " ")
+}
+
+// Man returns the OPTIONS section of the man text.
+func (opts Options) Man() string {
+	var b strings.Builder
+	b.WriteString("OPTIONS")
+	for _, o := range opts {
+		heading := strings.Join(append([]string{o.Name}, o.Aliases...),
+			", ")
+		if !o.IsBool() {
+			heading += " " + o.ArgName()
+		}
+		help := strings.Split(o.Help, "\n")
+		if len(o.Default) > 0 {
+			help[len(help)-1] += fmt.Sprint(" (default ", o.Default,
+				")")
+		}
+		b.WriteString("\n\t")
+		b.WriteString(heading)
+		if len(heading) < 7 && len(help) == 1 {
+			b.WriteString("\t")
+		} else {
+			b.WriteString("\n\t\t")
+		}
+		b.WriteString(strings.Join(help, "\n\t\t"))
+	}
+	return b.String()
+}
+
+// Complete returns the option names beginning with the last argument, the
+// values of the option preceding it, or otherwise, file names.
+func (opts Options) Complete(args ...string) (list []string) {
+	if len(args) == 0 {
+		return
+	}
+	prefix := args[len(args)-1]
+	if len(args) > 1 {
+		if o, found := opts.lookup(args[len(args)-2]); found &&
+			!o.IsBool() {
+			return o.complete(prefix)
+		}
+	}
+	if strings.HasPrefix(prefix, "-") {
+		for _, o := range opts {
+			for _, name := range append([]string{o.Name},
+				o.Aliases...) {
+				if strings.HasPrefix(name, prefix) {
+					list = append(list, name)
+				}
+			}
+		}
+		sort.Strings(list)
+		return
+	}
+	return File.Complete(prefix)
+}
+
+// Hints returns the option whose value is the last argument, or those
+// named with its prefix.
+func (opts Options) Hints(args ...string) (hints Options, isValue bool) {
+	var prefix string
+	if len(args) > 0 {
+		prefix = args[len(args)-1]
+	}
+	if len(args) > 1 {
+		if o, found := opts.lookup(args[len(args)-2]); found &&
+			!o.IsBool() {
+			return Options{o}, true
+		}
+	}
+	if len(prefix) > 0 && !strings.HasPrefix(prefix, "-") {
+		return
+	}
+	for _, o := range opts {
+		for _, name := range append([]string{o.Name}, o.Aliases...) {
+			if strings.HasPrefix(name, prefix) {
+				hints = append(hints, o)
+				break
+			}
+		}
+	}
+	return
+}
+
+func (opts Options) lookup(name string) (Option, bool) {
+	for _, o := range opts {
+		if o.Name == name {
+			return o, true
+		}
+		for _, aka := range o.Aliases {
+			if aka == name {
+				return o, true
+			}
+		}
+	}
+	return Option{}, false
+}
+
+// IsBool is true of options without a value.
+func (o Option) IsBool() bool { return o.Type == nil || o.Type == Bool }
+
+// ArgName returns the value placeholder of a non-boolean option.
+func (o Option) ArgName() string {
+	if len(o.Arg) > 0 {
+		return o.Arg
+	}
+	return strings.ToUpper(strings.TrimLeft(o.Name, "-"))
+}
+
+func (o Option) complete(prefix string) []string {
+	if o.Complete != nil {
+		return o.Complete(prefix)
+	}
+	return o.Type.Complete(prefix)
+}
+
+func (vals Values) Bool(name string) bool {
+	v, _ := vals[name].(bool)
+	return v
+}
+
+func (vals Values) Int(name string) int {
+	v, _ := vals[name].(int)
+	return v
+}
+
+func (vals Values) Duration(name string) time.Duration {
+	v, _ := vals[name].(time.Duration)
+	return v
+}
+
+// String returns the value of a String, Ifname, File or Choice option.
+func (vals Values) String(name string) string {
+	v, _ := vals[name].(string)
+	return v
+}
+
+func (vals Values) IP(name string) net.IP {
+	v, _ := vals[name].(net.IP)
+	return v
+}
+
+func (vals Values) CIDR(name string) *net.IPNet {
+	v, _ := vals[name].(*net.IPNet)
+	return v
+}
+
+// IsSet returns whether the option was given or has a default.
+func (vals Values) IsSet(name string) bool {
+	_, found := vals[name]
+	return found
+}
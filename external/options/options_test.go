@@ -0,0 +1,86 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package options
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testopts = Options{
+	{Name: "-v", Help: "verbose"},
+	{Name: "-x", Help: "trace"},
+	{Name: "-t", Type: Duration, Arg: "TIMEOUT", Default: "30",
+		Help: "timeout"},
+	{Name: "-c", Aliases: []string{"-count"}, Type: Int, Help: "count"},
+	{Name: "-mode", Type: Choice("fast", "slow"), Help: "mode"},
+}
+
+func TestParse(t *testing.T) {
+	opt, args, err := testopts.Parse([]string{"-vx", "-count", "3",
+		"-mode=slow", "FILE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(args, []string{"FILE"}) {
+		t.Error("wrong:", args)
+	}
+	if !opt.Bool("-v") || !opt.Bool("-x") {
+		t.Error("wrong:", opt)
+	}
+	if opt.Int("-c") != 3 {
+		t.Error("wrong:", opt.Int("-c"))
+	}
+	if opt.Duration("-t") != 30*time.Second {
+		t.Error("wrong:", opt.Duration("-t"))
+	}
+	if opt.String("-mode") != "slow" {
+		t.Error("wrong:", opt.String("-mode"))
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, _, err := testopts.Parse([]string{"-t", "soon"})
+	if err == nil || err.Error() != "soon: invalid timeout" {
+		t.Error("wrong:", err)
+	}
+	_, _, err = testopts.Parse([]string{"-mode", "medium"})
+	if err == nil || err.Error() != "medium: invalid mode" {
+		t.Error("wrong:", err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	s := testopts.Usage("test", "FILE")
+	if s != "test [-vx] [-t TIMEOUT] [-c C] [-mode MODE] FILE" {
+		t.Error("wrong:", s)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	if s := testopts.Complete("-m"); !reflect.DeepEqual(s,
+		[]string{"-mode"}) {
+		t.Error("wrong:", s)
+	}
+	if s := testopts.Complete("-mode", "f"); !reflect.DeepEqual(s,
+		[]string{"fast"}) {
+		t.Error("wrong:", s)
+	}
+}
+
+func TestHints(t *testing.T) {
+	hints, isValue := testopts.Hints("-t", "")
+	if !isValue || len(hints) != 1 || hints[0].ArgName() != "TIMEOUT" {
+		t.Error("wrong:", hints)
+	}
+	hints, isValue = testopts.Hints("-co")
+	if isValue || len(hints) != 1 || hints[0].Name != "-c" {
+		t.Error("wrong:", hints)
+	}
+	if hints, _ = testopts.Hints(""); len(hints) != len(testopts) {
+		t.Error("wrong:", hints)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package options
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Type parses and completes option values.
+type Type interface {
+	Parse(string) (interface{}, error)
+	Complete(prefix string) []string
+}
+
+var (
+	// Bool options are given without a value.
+	Bool Type = boolType{}
+	// Int values may be decimal, 0x hex or 0 octal.
+	Int Type = intType{}
+	// Duration values are seconds, e.g. 1.5, or with units, e.g. 1m30s.
+	Duration Type = durationType{}
+	String   Type = stringType{}
+	IP       Type = ipType{}
+	// CIDR values are an address and prefix length, e.g. 10.0.0.0/8
+	CIDR Type = cidrType{}
+	// Ifname values are network interface names, completed with those of
+	// /sys/class/net.
+	Ifname Type = ifnameType{}
+	// File values are completed with file names.
+	File Type = fileType{}
+)
+
+var errInvalid = errors.New("invalid")
+
+// Choice values are one of those given.
+func Choice(values ...string) Type { return choiceType(values) }
+
+type boolType struct{}
+
+func (boolType) Parse(s string) (interface{}, error) {
+	return strconv.ParseBool(s)
+}
+
+func (boolType) Complete(string) []string { return nil }
+
+type intType struct{}
+
+func (intType) Parse(s string) (interface{}, error) {
+	i, err := strconv.ParseInt(s, 0, 0)
+	return int(i), err
+}
+
+func (intType) Complete(string) []string { return nil }
+
+type durationType struct{}
+
+func (durationType) Parse(s string) (interface{}, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (durationType) Complete(string) []string { return nil }
+
+type stringType struct{}
+
+func (stringType) Parse(s string) (interface{}, error) { return s, nil }
+
+func (stringType) Complete(string) []string { return nil }
+
+type ipType struct{}
+
+func (ipType) Parse(s string) (interface{}, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		return ip, nil
+	}
+	return nil, errInvalid
+}
+
+func (ipType) Complete(string) []string { return nil }
+
+type cidrType struct{}
+
+func (cidrType) Parse(s string) (interface{}, error) {
+	_, ipnet, err := net.ParseCIDR(s)
+	return ipnet, err
+}
+
+func (cidrType) Complete(string) []string { return nil }
+
+type ifnameType struct{}
+
+func (ifnameType) Parse(s string) (interface{}, error) {
+	// IFNAMSIZ less the terminating NUL
+	if len(s) > 15 || strings.ContainsAny(s, "/ \t\n:") {
+		return nil, errInvalid
+	}
+	return s, nil
+}
+
+func (ifnameType) Complete(prefix string) (list []string) {
+	fis, _ := ioutil.ReadDir("/sys/class/net")
+	for _, fi := range fis {
+		if strings.HasPrefix(fi.Name(), prefix) {
+			list = append(list, fi.Name())
+		}
+	}
+	return
+}
+
+type fileType struct{}
+
+func (fileType) Parse(s string) (interface{}, error) { return s, nil }
+
+func (fileType) Complete(prefix string) []string {
+	list, _ := filepath.Glob(prefix + "*")
+	return list
+}
+
+type choiceType []string
+
+func (t choiceType) Parse(s string) (interface{}, error) {
+	for _, v := range t {
+		if s == v {
+			return s, nil
+		}
+	}
+	return nil, errInvalid
+}
+
+func (t choiceType) Complete(prefix string) (list []string) {
+	for _, v := range t {
+		if strings.HasPrefix(v, prefix) {
+			list = append(list, v)
+		}
+	}
+	return
+}
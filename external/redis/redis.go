@@ -6,6 +6,7 @@
 package redis
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -252,8 +253,15 @@ func Keys(pattern string) (keys []string, err error) {
 
 // Wait for the given (key, field) to have value or anything if value is "".
 func Hwait(key, field, value string, dur time.Duration) error {
+	return HwaitContext(context.Background(), key, field, value, dur)
+}
+
+// HwaitContext is Hwait that returns the context error if done first.
+func HwaitContext(ctx context.Context, key, field, value string, dur time.Duration) error {
 	const t = 250 * time.Millisecond
-	for end := time.Now().Add(dur); time.Now().Before(end); time.Sleep(t) {
+	tick := time.NewTicker(t)
+	defer tick.Stop()
+	for end := time.Now().Add(dur); time.Now().Before(end); {
 		s, err := Hget(key, field)
 		if err == nil && len(s) > 0 {
 			if len(value) > 0 && s != value {
@@ -262,6 +270,11 @@ func Hwait(key, field, value string, dur time.Duration) error {
 			}
 			return err
 		}
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 	return fmt.Errorf("(%s,%s) timeout", key, field)
 }
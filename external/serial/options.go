@@ -0,0 +1,109 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package serial
+
+import (
+	"syscall"
+
+	"github.com/platinasystems/goes/external/options"
+)
+
+var bauds = []struct {
+	name string
+	flag uint32
+}{
+	{"50", syscall.B50},
+	{"75", syscall.B75},
+	{"110", syscall.B110},
+	{"134", syscall.B134},
+	{"150", syscall.B150},
+	{"200", syscall.B200},
+	{"300", syscall.B300},
+	{"600", syscall.B600},
+	{"1200", syscall.B1200},
+	{"1800", syscall.B1800},
+	{"2400", syscall.B2400},
+	{"4800", syscall.B4800},
+	{"9600", syscall.B9600},
+	{"19200", syscall.B19200},
+	{"38400", syscall.B38400},
+	{"57600", syscall.B57600},
+	{"115200", syscall.B115200},
+	{"230400", syscall.B230400},
+	{"460800", syscall.B460800},
+	{"500000", syscall.B500000},
+	{"576000", syscall.B576000},
+	{"921600", syscall.B921600},
+	{"1000000", syscall.B1000000},
+	{"1152000", syscall.B1152000},
+	{"1500000", syscall.B1500000},
+	{"2000000", syscall.B2000000},
+	{"2500000", syscall.B2500000},
+	{"3000000", syscall.B3000000},
+	{"3500000", syscall.B3500000},
+	{"4000000", syscall.B4000000},
+}
+
+var cflags = map[string]map[string]uint32{
+	"-parity": {
+		"odd":  syscall.PARENB | syscall.PARODD,
+		"even": syscall.PARENB,
+		"none": 0,
+	},
+	"-databits": {
+		"5": syscall.CS5,
+		"6": syscall.CS6,
+		"7": syscall.CS7,
+		"8": syscall.CS8,
+	},
+	"-stopbits": {
+		"1": 0,
+		"2": syscall.CSTOPB,
+	},
+}
+
+// Options of the serial device of commands like femtocom and ldp.
+var Options = options.Options{
+	{Name: "-baud", Type: options.Choice(baudNames()...),
+		Default: "115200",
+		Help:    "The valid baud rates are 50 through 4000000."},
+	{Name: "-parity", Type: options.Choice("odd", "even", "none"),
+		Default: "none",
+		Help:    `The valid parity options are "odd", "even" and "none".`},
+	{Name: "-databits", Type: options.Choice("5", "6", "7", "8"),
+		Arg: "BITS", Default: "8",
+		Help: "The valid bits per character are 5, 6, 7 and 8."},
+	{Name: "-stopbits", Type: options.Choice("1", "2"),
+		Arg: "BITS", Default: "1",
+		Help: "The valid stop bits per character are 1 and 2."},
+	{Name: "-noinit",
+		Help: "Don't initialize the device at start-up or reset on exit."},
+	{Name: "-noreset",
+		Help: "Don't reset the device on exit."},
+	{Name: "-nolock",
+		Help: "Don't attempt exclusive device use."},
+}
+
+// Cflag returns the termios control modes of the parsed Options.
+func Cflag(opt options.Values) uint32 {
+	var flag uint32
+	for _, baud := range bauds {
+		if baud.name == opt.String("-baud") {
+			flag = baud.flag
+		}
+	}
+	for name, values := range cflags {
+		flag |= values[opt.String(name)]
+	}
+	return flag
+}
+
+func baudNames() []string {
+	names := make([]string, len(bauds))
+	for i, baud := range bauds {
+		names[i] = baud.name
+	}
+	return names
+}
@@ -0,0 +1,90 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A frame is the context of a function call or sourced script; that is,
+// its positional parameters and the caller's values of its local
+// variables.
+type frame struct {
+	args   []string
+	locals map[string]saved
+}
+
+type saved struct {
+	value string
+	set   bool
+}
+
+// PushFrame starts the context of a function call or sourced script with
+// the given positional parameters, $1, $2, etc.
+func (g *Goes) PushFrame(args []string) {
+	g.frames = append(g.frames, &frame{args: args})
+}
+
+// PopFrame ends the context of the last PushFrame and restores the
+// variables hidden by local.
+func (g *Goes) PopFrame() {
+	n := len(g.frames)
+	if n == 0 {
+		return
+	}
+	f := g.frames[n-1]
+	g.frames = g.frames[:n-1]
+	for k, v := range f.locals {
+		if v.set {
+			g.EnvMap[k] = v.value
+		} else {
+			delete(g.EnvMap, k)
+		}
+	}
+}
+
+// Args returns the positional parameters of the current function call or
+// sourced script.
+func (g *Goes) Args() []string {
+	if n := len(g.frames); n > 0 {
+		return g.frames[n-1].args
+	}
+	return nil
+}
+
+// Shift discards the first n positional parameters.
+func (g *Goes) Shift(n int) error {
+	args := g.Args()
+	if n < 0 || n > len(args) {
+		return fmt.Errorf("%d: shift count out of range", n)
+	}
+	if n > 0 {
+		g.frames[len(g.frames)-1].args = args[n:]
+	}
+	return nil
+}
+
+// Local sets a variable until the end of the current function call or
+// sourced script.
+func (g *Goes) Local(name, value string) error {
+	n := len(g.frames)
+	if n == 0 {
+		return errors.New("can only be used in a function")
+	}
+	f := g.frames[n-1]
+	if f.locals == nil {
+		f.locals = make(map[string]saved)
+	}
+	if g.EnvMap == nil {
+		g.EnvMap = make(map[string]string)
+	}
+	if _, found := f.locals[name]; !found {
+		v, set := g.EnvMap[name]
+		f.locals[name] = saved{value: v, set: set}
+	}
+	g.EnvMap[name] = value
+	return nil
+}
@@ -10,6 +10,9 @@ package goes
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -20,15 +23,14 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 	"unicode/utf8"
 
 	"github.com/platinasystems/goes/cmd"
 	"github.com/platinasystems/goes/external/flags"
-	"github.com/platinasystems/goes/external/parms"
 	"github.com/platinasystems/goes/internal/prog"
 	"github.com/platinasystems/goes/internal/shellutils"
 	"github.com/platinasystems/goes/lang"
-	"github.com/platinasystems/url"
 )
 
 const (
@@ -63,6 +65,18 @@ type Goes struct {
 	Status    error
 	Verbosity int
 
+	// ErrExit terminates a script on the failure of any command that
+	// isn't a condition (set -e). PipeFail sets the status of a pipeline
+	// to that of its last failing stage rather than its last stage
+	// (set -o pipefail).
+	ErrExit, PipeFail bool
+
+	// ErrAbort terminates a script on the error of a command run
+	// in-process, e.g. cd, or not found, rather than the failed exit
+	// status of a forked command. The cli sets this for a script unless
+	// run with -f.
+	ErrAbort bool
+
 	cache  cache
 	parent *Goes
 
@@ -70,9 +84,51 @@ type Goes struct {
 
 	FunctionMap map[string]Function
 
+	// AliasMap is the command text substituted for the first word of a
+	// command of the same name.
+	AliasMap map[string]string
+
+	// frames are the contexts of the running functions and sourced
+	// scripts
+	frames []*frame
+
 	inTest bool
+
+	// JobControl runs each foreground pipeline in its own process group
+	// so that it may be stopped and continued as a job.
+	JobControl bool
+
+	jobs jobs
+
+	// pipes are the write ends of the running pipelines
+	pipes []*os.File
+
+	// lastChild is the last forked pipeline stage not waited upon
+	lastChild *child
+
+	// ctx is that of the running command, see Context
+	ctx context.Context
+
+	// linter, if set, checks each command instead of it being run
+	linter *linter
+
+	// shell, if set, is that running the pipeline of this stage Goes
+	shell *Goes
+
+	// stdioHeld is set while an in-process command holds stdioMutex
+	stdioHeld bool
+
+	// files are the standard files of a stage, those of its shell as it
+	// started the pipeline; see std
+	files [3]*os.File
+
+	// mains counts the nested runs of Main, e.g. of in-process commands
+	mains int
 }
 
+// A Function is run by name like a command. Its Definition, if any, is
+// the source text by which a concurrent pipeline stage defines its own
+// copy.
 type Function struct {
 	Name       string
 	Definition []string
@@ -102,13 +158,147 @@ var (
 	WG     sync.WaitGroup
 )
 
+// ErrInterrupted is returned by commands and lists stopped with ^C.
+var ErrInterrupted = errors.New("Command interrupted")
+
+// ErrBrokenPipe unwinds an in-process pipeline stage once the next stage no
+// longer reads its output, as a forked stage is killed by SIGPIPE.
+var ErrBrokenPipe = errors.New("broken pipe")
+
+// ExitError is returned by a command list that failed with ErrExit set.
+// Unlike other errors it isn't reported and recorded as the list status but
+// instead unwinds all enclosing blocks and functions to terminate the
+// script.
+type ExitError struct {
+	Err error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// LoopError is returned by break and continue to unwind the enclosing
+// blocks to the Nth enclosing for, while, or until loop.
+type LoopError struct {
+	N        int
+	Continue bool
+}
+
+func (e *LoopError) Error() string {
+	if e.Continue {
+		return "continue: only meaningful in a loop"
+	}
+	return "break: only meaningful in a loop"
+}
+
+// ReturnError is returned by return to unwind the enclosing blocks of a
+// function or sourced script and end it with the given exit status.
+type ReturnError struct {
+	Status int
+}
+
+func (e *ReturnError) Error() string {
+	return "return: can only return from a function or sourced script"
+}
+
+// StatusError is a failure with the given exit status that, unlike other
+// errors, isn't reported, e.g. return 2.
+type StatusError int
+
+func (e StatusError) Error() string {
+	return fmt.Sprint("exit status ", int(e))
+}
+
+// IsUnwinding reports whether a list error should terminate enclosing
+// blocks rather than just set the status. This includes the error of a
+// cancelled or timed out context.
+func IsUnwinding(err error) bool {
+	var (
+		exit    *ExitError
+		stopped *StoppedError
+		loop    *LoopError
+		ret     *ReturnError
+	)
+	return errors.Is(err, ErrInterrupted) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrBrokenPipe) || errors.As(err, &exit) ||
+		errors.As(err, &stopped) || errors.As(err, &loop) ||
+		errors.As(err, &ret)
+}
+
+// Loop returns whether a loop should stop after its body returned err and,
+// if so, the error for the loop to return. A break or continue of an outer
+// loop is returned for that loop.
+func (g *Goes) Loop(err error) (bool, error) {
+	var loop *LoopError
+	if !errors.As(err, &loop) {
+		return err != nil, err
+	}
+	if loop.N > 1 {
+		return true, &LoopError{N: loop.N - 1, Continue: loop.Continue}
+	}
+	g.Status = nil
+	return !loop.Continue, nil
+}
+
+// Return sets the status of a function or sourced script ended by return.
+// Other errors are returned as is.
+func (g *Goes) Return(err error) error {
+	var ret *ReturnError
+	if !errors.As(err, &ret) {
+		return err
+	}
+	g.Status = nil
+	if ret.Status != 0 {
+		g.Status = StatusError(ret.Status)
+	}
+	return nil
+}
+
+// ExitStatus returns the shell exit code of a command status; that is 0
+// for success, the exit code or 128 plus the signal number of a child, and
+// 1 otherwise.
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var stopped *StoppedError
+	if errors.As(err, &stopped) {
+		return 128 + int(syscall.SIGTSTP)
+	}
+	if errors.Is(err, ErrBrokenPipe) {
+		return 128 + int(syscall.SIGPIPE)
+	}
+	var status StatusError
+	if errors.As(err, &status) {
+		return int(status)
+	}
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal())
+		}
+		return ee.ExitCode()
+	}
+	return 1
+}
+
+// Condition runs the test of an if, while, or until block with ErrExit
+// and ErrAbort suspended.
+func (g *Goes) Condition(f func() error) error {
+	errexit, errabort := g.ErrExit, g.ErrAbort
+	g.ErrExit, g.ErrAbort = false, false
+	defer func() { g.ErrExit, g.ErrAbort = errexit, errabort }()
+	return f()
+}
+
 func (g *Goes) ProcessPipeline(ls shellutils.List) (*shellutils.List, *shellutils.Word, func(io.Reader, io.Writer, io.Writer) error, error) {
 	var (
 		closers []io.Closer
 		term    shellutils.Word
 	)
 	isLast := false
-	pipeline := make([]func(io.Reader, io.Writer, io.Writer) error, 0)
+	pipeline := make([]Stage, 0)
 	for len(ls.Cmds) != 0 && !isLast {
 		cl := ls.Cmds[0]
 		term = cl.Term
@@ -116,36 +306,77 @@ func (g *Goes) ProcessPipeline(ls shellutils.List) (*shellutils.List, *shellutil
 			isLast = true
 		}
 
-		var runfun func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error
 		name := cl.Cmds[0].String()
-		if v := g.ByName[name]; v != nil {
-			if method, found := v.(Blocker); found {
-				var (
-					newls *shellutils.List
-					err   error
-				)
-				newls, runfun, err = method.Block(g, ls)
+		var block func(*Goes, shellutils.List) (*shellutils.List, func(io.Reader, io.Writer, io.Writer) error, error)
+		inProcess := func() bool { return true }
+		if isGroup(cl) {
+			block = (*Goes).Group
+			if name == "(" {
+				inProcess = func() bool { return false }
+			}
+		} else if method, found := g.ByName[name].(Blocker); found {
+			block = method.Block
+		}
+		if block != nil {
+			// a block is only known to be other than the last stage
+			// once parsed, it's then parsed again for its own Goes
+			src := shellutils.List{
+				Cmds: append([]shellutils.Cmdline(nil), ls.Cmds...),
+			}
+			var rec *recorder
+			catline := g.Catline
+			if catline != nil {
+				rec = &recorder{ReadWriter: catline}
+				g.Catline = rec
+			}
+			newls, runfun, err := block(g, ls)
+			g.Catline = catline
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			sg := g
+			if newls.Cmds[0].Term.String() == "|" && g.linter == nil {
+				sg = g.stage()
+				var text lines
+				if rec != nil {
+					text = lines(rec.lines)
+				}
+				sg.Catline = &text
+				newls, runfun, err = block(sg, src)
 				if err != nil {
 					return nil, nil, nil, err
 				}
-
-				ls = *newls
-				cl = ls.Cmds[0]
-				ls.Cmds = ls.Cmds[1:]
-				term = cl.Term
-				if term.String() != "|" {
-					isLast = true
-				}
-				pipeline = append(pipeline, runfun)
-				continue
 			}
+
+			ls = *newls
+			cl = ls.Cmds[0]
+			ls.Cmds = ls.Cmds[1:]
+			term = cl.Term
+			isLast = term.String() != "|"
+			pipeline = append(pipeline, Stage{
+				Run:       runfun,
+				InProcess: inProcess,
+				g:         sg,
+			})
+			continue
+		}
+		stage := Stage{g: g}
+		if !isLast && g.linter == nil {
+			stage.g = g.stage()
+			stage.closers = new([]io.Closer)
+		}
+		var err error
+		if stage.closers != nil {
+			stage.Run, err = stage.g.ProcessCommand(cl, stage.closers)
+		} else {
+			stage.Run, err = g.ProcessCommand(cl, &closers)
 		}
-		runfun, err := g.ProcessCommand(cl, &closers)
 		if err != nil {
 			return nil, nil, nil, err
 		}
 		ls.Cmds = ls.Cmds[1:]
-		pipeline = append(pipeline, runfun)
+		stage.InProcess = stage.g.isInProcess(cl)
+		pipeline = append(pipeline, stage)
 	}
 
 	pipefun, err := g.MakePipefun(pipeline, &closers)
@@ -154,7 +385,7 @@ func (g *Goes) ProcessPipeline(ls shellutils.List) (*shellutils.List, *shellutil
 
 func (g *Goes) isStdinRedirected(stdin io.Reader) bool {
 	if f, ok := stdin.(*os.File); ok {
-		if f == os.Stdin {
+		if in, _, _ := g.std(); f == in {
 			return false
 		}
 		return true
@@ -164,7 +395,7 @@ func (g *Goes) isStdinRedirected(stdin io.Reader) bool {
 
 func (g *Goes) isStdoutRedirected(stdout io.Writer) bool {
 	if f, ok := stdout.(*os.File); ok {
-		if f == os.Stdout {
+		if _, out, _ := g.std(); f == out {
 			return false
 		}
 		return true
@@ -172,9 +403,19 @@ func (g *Goes) isStdoutRedirected(stdout io.Writer) bool {
 	return true
 }
 
+// isPipe reports whether the writer is that of a pipeline stage.
+func (g *Goes) isPipe(w io.Writer) bool {
+	for _, p := range g.pipes {
+		if w == io.Writer(p) {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *Goes) isStderrRedirected(stderr io.Writer) bool {
 	if f, ok := stderr.(*os.File); ok {
-		if f == os.Stderr {
+		if _, _, errw := g.std(); f == errw {
 			return false
 		}
 		return true
@@ -193,21 +434,22 @@ func (g *Goes) errOrInt(err error) error {
 	}
 	select {
 	case <-IntSig:
-		return fmt.Errorf("Command interrupted")
+		return ErrInterrupted
 	default:
 	}
 	return nil
 }
 
 func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
-	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
-		envMap, args := cl.Slice(func(k string) string {
-			v, def := g.EnvMap[k]
-			if def {
-				return v
-			}
-			return os.Getenv(k)
-		})
+	if g.linter != nil {
+		g.linter.command(g, cl)
+	}
+	run := func(au *audited, stdin io.Reader, stdout io.Writer, stderr io.Writer) (err error) {
+		// the rest of a list is skipped once its context is done
+		if err := g.Context().Err(); err != nil {
+			return err
+		}
+		envMap, args := cl.SliceSubst(g.Getenv, g.Cmdsubst)
 		// Add to our context environment if this command only set variables
 		if len(args) == 0 {
 			if len(envMap) != 0 {
@@ -222,14 +464,47 @@ func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func
 			}
 			return nil
 		}
+		args = g.expandAlias(args)
+		au.args = args
+		args, in, out, errw, err := g.redirect(args, stdin, stdout, stderr,
+			closers)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			// just the redirections
+			return nil
+		}
 		name := args[0]
+		// the commands of functions are authorized rather than the
+		// call; redirections are authorized by themselves
+		if _, found := g.FunctionMap[name]; !found {
+			if err := authorize(args); err != nil {
+				return err
+			}
+		}
+		// errors of commands with redirected stderr are reported there
+		if errw != stderr {
+			defer func() {
+				var status StatusError
+				if err != nil && !IsUnwinding(err) {
+					if !errors.As(err, &status) {
+						fmt.Fprintln(errw, err)
+					}
+					g.Status, err = err, nil
+					au.err = g.Status
+				}
+			}()
+		}
 		// check for function invocation
 
 		if f, x := g.FunctionMap[name]; x {
-			return g.errOrInt(f.RunFun(stdin, stdout, stderr))
+			g.PushFrame(args[1:])
+			defer g.PopFrame()
+			return g.errOrInt(f.RunFun(in, out, errw))
 		}
 		// check for built in command
-		if v := g.ByName[name]; v != nil {
+		if v, _ := g.lookup(name); v != nil {
 			k := cmd.WhatKind(v)
 			if k.IsDaemon() {
 				return fmt.Errorf(
@@ -241,97 +516,24 @@ func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func
 					return fmt.Errorf("%s: can't pipe", name)
 				}
 			}
-			if k.IsDontFork() || g.inTest ||
-				name == os.Args[0] {
+			if (k.IsDontFork() || g.inTest ||
+				name == os.Args[0]) && !g.mustFork(in, out, errw) {
 				if method, found := v.(goeser); found {
 					method.Goes(g)
 				}
-				return g.errOrInt(g.Main(args...))
+				return g.errOrInt(g.stdio(in, out, errw,
+					func() error {
+						return g.foreground(args)
+					}))
 			}
-		} else if builtin, found := g.Builtins()[name]; found {
-			return builtin(args[1:]...)
-		} else {
+		} else if builtin, found := g.Builtins()[name]; !found {
 			return fmt.Errorf("%s: command not found", name)
-		}
-		in := stdin
-		if !g.isStdinRedirected(stdin) {
-			var iparm *parms.Parms
-			iparm, args = parms.New(args, "<", "<<", "<<-")
-			if fn := iparm.ByName["<"]; len(fn) > 0 {
-				rc, err := url.Open(fn)
-				if err != nil {
-					return err
-				}
-				in = rc
-				*closers = append(*closers, rc)
-			} else if len(iparm.ByName["<<"]) > 0 ||
-				len(iparm.ByName["<<-"]) > 0 {
-				var trim bool
-				lbl := iparm.ByName["<<"]
-				if len(lbl) == 0 {
-					lbl = iparm.ByName["<<-"]
-					trim = true
-				}
-				r, w, err := os.Pipe()
-				if err != nil {
-					return err
-				}
-				in = r
-				*closers = append(*closers, r)
-				WG.Add(1)
-				go func(w io.WriteCloser, lbl string) {
-					defer WG.Done()
-					defer w.Close()
-					prompt := "<<" + fn + " "
-					for {
-						g.Catline.Write([]byte(prompt))
-						buf := make([]byte, 1024)
-						n, err := g.Catline.Read(buf)
-						s := string(buf[0:n])
-						if err != nil || s == lbl {
-							break
-						}
-						if trim {
-							s = strings.TrimLeft(s, " \t")
-						}
-						fmt.Fprintln(w, s)
-					}
-				}(w, lbl)
-			}
-		}
-		out := stdout
-		if !g.isStdoutRedirected(stdout) {
-			var oparm *parms.Parms
-			oparm, args = parms.New(args, ">", ">>", ">>>", ">>>>")
-			if fn := oparm.ByName[">"]; len(fn) > 0 {
-				wc, err := url.Create(fn)
-				if err != nil {
-					return err
-				}
-				out = wc
-				*closers = append(*closers, wc)
-			} else if fn = oparm.ByName[">>"]; len(fn) > 0 {
-				wc, err := url.Append(fn)
-				if err != nil {
-					return err
-				}
-				out = wc
-				*closers = append(*closers, wc)
-			} else if fn := oparm.ByName[">>>"]; len(fn) > 0 {
-				wc, err := url.Create(fn)
-				if err != nil {
-					return err
-				}
-				out = io.MultiWriter(os.Stdout, wc)
-				*closers = append(*closers, wc)
-			} else if fn := oparm.ByName[">>"]; len(fn) > 0 {
-				wc, err := url.Append(fn)
-				if err != nil {
-					return err
-				}
-				out = io.MultiWriter(os.Stdout, wc)
-				*closers = append(*closers, wc)
-			}
+		} else if !g.mustFork(in, out, errw) {
+			g.Status = g.stdio(in, out, errw,
+				func() error {
+					return builtin(args[1:]...)
+				})
+			return g.Status
 		}
 		var envStr []string
 		if len(envMap) != 0 {
@@ -350,83 +552,326 @@ func (g *Goes) ProcessCommand(cl shellutils.Cmdline, closers *[]io.Closer) (func
 				x.Env = append(x.Env, s)
 			}
 		}
+		x.Env = auditEnv(roleEnv(x.Env))
 		x.Stdin = in
 		x.Stdout = out
-		x.Stderr = stderr
+		x.Stderr = errw
 
+		isPipe := g.isPipe(stdout)
+		// the stages of a pipeline may start concurrently
+		j := g.pipeline()
+		j.Lock()
+		if g.JobControl {
+			x.SysProcAttr = &syscall.SysProcAttr{
+				Setpgid:    true,
+				Pgid:       j.pgid,
+				Foreground: j.pgid == 0,
+			}
+		}
 		if err := x.Start(); err != nil {
+			j.Unlock()
 			err = fmt.Errorf("child: %v: %v", x.Args, err)
 			return err
 		}
-		if !g.isStdoutRedirected(stdout) { // fixme not a pipe
-			err := x.Wait()
+		if g.JobControl && j.pgid == 0 {
+			j.pgid = x.Process.Pid
+		}
+		j.Unlock()
+		c := g.watch(x, isPipe)
+		g.killOnDone(c)
+		if g.JobControl {
+			j.Lock()
+			j.procs = append(j.procs, c)
+			j.Unlock()
+		}
+		if !isPipe {
+			var err error
+			if g.JobControl && !g.inStage() {
+				var text []string
+				j.Lock()
+				job := &Job{
+					Pgid:  j.pgid,
+					procs: j.procs,
+				}
+				j.Unlock()
+				for _, p := range job.procs {
+					text = append(text,
+						strings.Join(p.x.Args, " "))
+				}
+				job.Text = strings.Join(text, " | ")
+				err = g.waitForeground(job)
+				if IsUnwinding(err) {
+					return err
+				}
+			} else {
+				<-c.done
+				err = c.err
+				if g.JobControl {
+					interrupted(err)
+				}
+			}
+			if isBrokenPipe(err) {
+				return ErrBrokenPipe
+			}
 			g.Status = err
-			if err != nil &&
+			au.err = err
+			// a child stopped by its context isn't reported
+			if err != nil && g.Context().Err() == nil &&
 				err.Error() != "exit status 1" {
-				fmt.Fprintln(os.Stderr, err)
+				_, _, f := g.std()
+				fmt.Fprintln(f, err)
 			}
 		} else {
-			WG.Add(1)
-			go func(x *exec.Cmd) {
-				defer WG.Done()
-				err := x.Wait()
-				if err != nil &&
-					err.Error() != "exit status 1" {
-					fmt.Fprintln(os.Stderr, err)
-				}
-				if x.Stdout != os.Stdout {
-					m, found := x.Stdout.(io.Closer)
-					if found {
-						m.Close()
-					}
-				}
-				if x.Stdin != os.Stdin {
-					m, found := x.Stdin.(io.Closer)
-					if found {
-						m.Close()
-					}
-				}
-			}(x)
+			g.lastChild = c
+			au.piped = c
 		}
 		return g.errOrInt(nil)
 	}
+	runfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+		var au audited
+		if !IsAudited() || g.linter != nil {
+			return run(&au, stdin, stdout, stderr)
+		}
+		start := time.Now()
+		err := run(&au, stdin, stdout, stderr)
+		switch {
+		case au.args == nil:
+			// only set variables
+		case au.piped != nil:
+			go func() {
+				<-au.piped.done
+				Audit(au.args, start, au.piped.err)
+			}()
+		case err != nil:
+			Audit(au.args, start, err)
+		default:
+			Audit(au.args, start, au.err)
+		}
+		return err
+	}
 	return runfun, nil
 }
 
-func (g *Goes) MakePipefun(pipeline []func(io.Reader, io.Writer, io.Writer) error, closers *[]io.Closer) (func(io.Reader, io.Writer, io.Writer) error, error) {
-	pipefun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
-		var (
-			err error
-			pin *os.File
-		)
+// audited are the args of a run command with its reported status or piped
+// child for its audit.
+type audited struct {
+	args  []string
+	err   error
+	piped *child
+}
+
+// A Stage of a pipeline is the runner of a command or block and whether
+// it runs in-process, i.e. is a block, function, builtin or DontFork
+// command, rather than forking.
+type Stage struct {
+	Run       func(io.Reader, io.Writer, io.Writer) error
+	InProcess func() bool
+
+	// g is that of the stage, see stage, and closers are the files of
+	// its redirections if not those of the pipeline.
+	g       *Goes
+	closers *[]io.Closer
+}
+
+// MakePipefun returns a function that runs each stage of the pipeline and
+// sets the status of the pipeline. Errors of the intermediate stages are
+// reported on stderr; that of the last stage is returned.
+//
+// Forked stages are connected with an os.Pipe. Each in-process stage other
+// than the last runs concurrently in its own go-routine and writes to an
+// io.Pipe that's closed when done, so that the next stage sees EOF. Its
+// input is also closed so that an earlier stage fails to write rather
+// than block. The pipeline returns once all in-process stages are done.
+func (g *Goes) MakePipefun(pipeline []Stage, closers *[]io.Closer) (func(io.Reader, io.Writer, io.Writer) error, error) {
+	pipefun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) (err error) {
+		var next io.Reader
 		defer func() {
 			for _, c := range *closers {
 				c.Close()
 			}
+			*closers = (*closers)[:0]
+		}()
+		// in a test, the in-process stages take their own turns with
+		// the standard files, so those of an enclosing command are
+		// released until all are done; see stdio
+		released := false
+		defer func() {
+			if released {
+				stdioMutex.Lock()
+				g.stdioHeld = true
+			}
 		}()
+		// each pipeline is a job of its own, other than those of a
+		// stage that are of the job of the enclosing pipeline
+		pipes := len(g.pipes)
+		defer func() { g.pipes = g.pipes[:pipes] }()
+		if g.shell == nil {
+			g.jobs.Lock()
+			pgid, procs := g.jobs.pgid, g.jobs.procs
+			g.jobs.pgid, g.jobs.procs = 0, nil
+			g.jobs.Unlock()
+			defer func() {
+				g.jobs.Lock()
+				fg := g.jobs.pgid != 0
+				g.jobs.pgid, g.jobs.procs = pgid, procs
+				g.jobs.Unlock()
+				if fg {
+					// in case not waited as a foreground job
+					g.setTerminalPgrp(g.jobs.pgrp)
+				}
+			}()
+		}
+		status := make([]error, len(pipeline))
+		children := make([]*child, len(pipeline))
+		stages := make([]*child, 0, len(pipeline))
+		defer func() {
+			if err == nil {
+				err = g.waitStages(stages)
+			} else {
+				g.waitStages(stages)
+			}
+		}()
+		// the stages have the standard files of the shell as it
+		// starts them, see std
+		var files [3]*os.File
+		files[0], files[1], files[2] = g.std()
 		in := stdin
 		end := len(pipeline) - 1
-		for i, runfun := range pipeline {
+		for i, stage := range pipeline {
+			sg := stage.g
+			if sg == nil {
+				sg = g
+			} else if sg != g {
+				sg.inherit(g)
+				sg.files = files
+			}
+			if i != end && stage.InProcess() {
+				if g.stdioHeld {
+					g.stdioHeld = false
+					stdioMutex.Unlock()
+					released = true
+				}
+				pr, pw := io.Pipe()
+				children[i] = sg.goStage(stage, in, pw, stderr,
+					i > 0)
+				stages = append(stages, children[i])
+				in = pr
+				continue
+			}
 			out := stdout
 			if i != end {
-				var pout *os.File
-				pin, pout, err = os.Pipe()
+				pr, pw, err := os.Pipe()
 				if err != nil {
-					break
+					return err
 				}
-				out = pout
+				out, next = pw, pr
+				sg.pipes = append(sg.pipes, pw)
 			}
-			err = runfun(in, out, stderr)
-			if err != nil {
-				break
+			sg.lastChild = nil
+			err = stage.Run(in, out, stderr)
+			children[i] = sg.lastChild
+			if stage.closers != nil {
+				*closers = append(*closers, *stage.closers...)
+				*stage.closers = (*stage.closers)[:0]
+			}
+			if i > 0 && (i == end || children[i] == nil) {
+				// the stage is done or has its own copy
+				in.(io.Closer).Close()
+			}
+			if i != end && children[i] == nil {
+				out.(io.Closer).Close()
+			}
+			if IsUnwinding(err) {
+				return err
+			}
+			if err != nil && i != end {
+				fmt.Fprintln(stderr, err)
+			}
+			if err != nil || children[i] == nil {
+				status[i] = sg.Status
+				if err != nil {
+					status[i] = err
+				}
+			}
+			in = next
+		}
+		g.Status = status[end]
+		if g.PipeFail {
+			for i, c := range children {
+				if c != nil {
+					<-c.done
+					status[i] = c.err
+				}
+			}
+			for i := end; i >= 0; i-- {
+				if status[i] != nil {
+					g.Status = status[i]
+					break
+				}
 			}
-			in = pin
 		}
 		return err
 	}
 	return pipefun, nil
 }
 
+// goStage runs an in-process pipeline stage in its own go-routine. Its
+// status is that of the returned child once done. Like forked stages,
+// such a stage can't exit or otherwise unwind the enclosing blocks.
+func (g *Goes) goStage(stage Stage, in io.Reader, out io.WriteCloser, stderr io.Writer, closeIn bool) *child {
+	c := &child{done: make(chan struct{})}
+	j := g.pipeline()
+	j.Lock()
+	j.stages++
+	j.Unlock()
+	go func() {
+		defer close(c.done)
+		defer func() {
+			j.Lock()
+			j.stages--
+			j.Unlock()
+		}()
+		err := stage.Run(in, out, stderr)
+		if stage.closers != nil {
+			for _, c := range *stage.closers {
+				c.Close()
+			}
+			*stage.closers = (*stage.closers)[:0]
+		}
+		out.Close()
+		if closeIn {
+			in.(io.Closer).Close()
+		}
+		var exit *ExitError
+		switch {
+		case errors.As(err, &exit):
+			err = exit.Err
+		case errors.Is(err, ErrInterrupted),
+			errors.Is(err, ErrBrokenPipe):
+		case IsUnwinding(err):
+			err = nil
+		case err != nil:
+			fmt.Fprintln(stderr, err)
+		default:
+			err = g.Status
+		}
+		c.err = err
+	}()
+	return c
+}
+
+// waitStages waits for the in-process stages of a pipeline to finish.
+func (g *Goes) waitStages(stages []*child) error {
+	for _, c := range stages {
+		select {
+		case <-c.done:
+		case <-IntSig:
+			return ErrInterrupted
+		}
+	}
+	return nil
+}
+
 func Replace(s, name string) string {
 	return strings.Replace(s, "goes", name, -1)
 }
@@ -464,9 +909,29 @@ func (g *Goes) Fork(args ...string) *exec.Cmd {
 //
 // Similarly for "-apropos", "-complete", "-man", and "-usage".
 //
+// If the args of a cmd.JSONer have "-json", this prints the JSON encoding
+// of its result rather than running its Main.
+//
+// A cmd.MainContexter runs with the Context of this goes, see MainContext.
+//
 // If the command is a daemon, this fork exec's itself twice to disassociate
 // the daemon from the tty and initiating process.
+//
+// The program exits with the status of a command that fails with just a
+// StatusError, e.g. lint, rather than returning it to be reported.
 func (g *Goes) Main(args ...string) error {
+	g.mains++
+	err := g.main(args...)
+	g.mains--
+	var status StatusError
+	if g.mains == 0 && g.parent == nil && g.shell == nil && !g.inTest &&
+		errors.As(err, &status) {
+		os.Exit(int(status))
+	}
+	return err
+}
+
+func (g *Goes) main(args ...string) error {
 	_ = syscall.Setrlimit(syscall.RLIMIT_CORE,
 		&syscall.Rlimit{Cur: 0xffffffff, Max: 0xffffffff})
 	Stop = make(chan struct{})
@@ -497,13 +962,24 @@ func (g *Goes) Main(args ...string) error {
 		}
 	}
 
-	IntSig = make(chan os.Signal, 1)
+	if IntSig == nil {
+		IntSig = make(chan os.Signal, 1)
+	}
+
+	// those of the shell that forked this cli, if any
+	g.defineFunctions()
+
+	// the plugins of the top goes are found as it starts, unless just to
+	// run another of its commands; see rehash
+	if len(args) == 0 || args[0] == "cli" || g.ByName[args[0]] == nil {
+		g.loadPlugins()
+	}
 
 	var v cmd.Cmd
 	var k cmd.Kind
 	var found bool
 	if len(args) > 0 {
-		v, found = g.ByName[args[0]]
+		v, found = g.lookup(args[0])
 		if found {
 			k = cmd.WhatKind(v)
 		}
@@ -513,7 +989,7 @@ func (g *Goes) Main(args ...string) error {
 		if clifound {
 			cli.(goeser).Goes(g)
 		}
-		cliFlags, cliArgs := flags.New(args, "-debug", "-f", "-no-liner", "-x")
+		cliFlags, cliArgs := flags.New(args, "-debug", "-e", "-f", "-n", "-no-liner", "-x")
 		if cliFlags.ByName["-debug"] && g.Verbosity < VerboseDebug {
 			g.Verbosity = VerboseDebug
 		}
@@ -534,24 +1010,24 @@ func (g *Goes) Main(args ...string) error {
 			fmt.Println(Usage(g))
 			g.Status = nil
 			return nil
-		} else if n == 1 {
-			// only check for script if args[0] isn't a command
-			buf, err := ioutil.ReadFile(cliArgs[0])
-			if cliArgs[0] == "-" || (err == nil && utf8.Valid(buf) &&
+		} else if buf, err := ioutil.ReadFile(cliArgs[0]); cliArgs[0] == "-" ||
+			(err == nil && utf8.Valid(buf) &&
 				bytes.HasPrefix(buf, []byte("#!/usr/bin/goes"))) {
-				// e.g. /usr/bin/goes SCRIPT
-				if cli == nil {
-					g.Status = fmt.Errorf("has no cli")
-					return g.Status
-				}
-				for _, t := range []string{"-f", "-x"} {
-					if cliFlags.ByName[t] {
-						cliArgs = append(cliArgs, t)
-					}
-				}
-				g.Status = cli.Main(cliArgs...)
+			// only check for script if args[0] isn't a command
+			// e.g. /usr/bin/goes SCRIPT [ARG]...
+			if cli == nil {
+				g.Status = fmt.Errorf("has no cli")
 				return g.Status
 			}
+			var opts []string
+			for _, t := range []string{"-e", "-f", "-n", "-x"} {
+				if cliFlags.ByName[t] {
+					opts = append(opts, t)
+				}
+			}
+			g.Status = cli.Main(append(opts, cliArgs...)...)
+			return g.Status
+		} else if n == 1 {
 			args = cliArgs
 		} else {
 			g.swap(args)
@@ -563,7 +1039,7 @@ func (g *Goes) Main(args ...string) error {
 	} else if len(args) == 1 && strings.HasPrefix(args[0], "-") {
 		arg0 := strings.TrimLeft(args[0], "-")
 		if arg0 == "apropos" {
-			fmt.Println(g.Apropos())
+			fmt.Println(g.aproposOf("", g))
 			return nil
 		} else if builtin, found := g.Builtins()[arg0]; found {
 			g.Status = builtin()
@@ -572,7 +1048,7 @@ func (g *Goes) Main(args ...string) error {
 	}
 
 	if g.shift(args) {
-		v, found = g.ByName[args[0]]
+		v, found = g.lookup(args[0])
 	}
 
 	if g.Verbosity >= VerboseDebug {
@@ -612,14 +1088,34 @@ func (g *Goes) Main(args ...string) error {
 				}
 			}
 		}()
-		err := v.Main(args[1:]...)
+		var err error
+		if method, found := v.(cmd.MainContexter); found {
+			ctx, cancel := cancelOnTerm(g.Context())
+			err = method.MainContext(ctx, args[1:]...)
+			cancel()
+		} else {
+			err = v.Main(args[1:]...)
+		}
 		close(quit)
 		WG.Wait()
 		signal.Stop(sig)
 		return err
 	}
 
-	err := v.Main(args[1:]...)
+	if method, found := v.(cmd.JSONer); found {
+		flag, jsonArgs := flags.New(args[1:], "-json")
+		if flag.ByName["-json"] {
+			g.Status = printJSON(method, jsonArgs)
+			return g.Status
+		}
+	}
+
+	var err error
+	if method, found := v.(cmd.MainContexter); found {
+		err = method.MainContext(g.Context(), args[1:]...)
+	} else {
+		err = v.Main(args[1:]...)
+	}
 	if err != nil && !k.IsDaemon() {
 		name := args[0]
 		if len(name) == 0 {
@@ -634,6 +1130,17 @@ func (g *Goes) Main(args ...string) error {
 	return err
 }
 
+// printJSON prints the JSON encoding of the command's result.
+func printJSON(v cmd.JSONer, args []string) error {
+	obj, err := v.JSON(args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", v, err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(obj)
+}
+
 // shift the first unambiguous longest prefix match command to args[0], so,
 //
 //	OPTIONS... COMMAND [ARGS]...
@@ -744,48 +1251,92 @@ func (g *Goes) ProcessList(ls shellutils.List) (*shellutils.List, *shellutils.Wo
 		return nil, nil, nil, err
 	}
 	ls = *newls
+
+	// Blocks may consume and alter the list, so the source text of a
+	// background job is taken beforehand along with any lines read.
+	before := ls.Cmds
+	quoted := make([]string, len(before))
+	for i := range before {
+		quoted[i] = before[i].Quote()
+	}
+	var rec *recorder
+	if g.Catline != nil {
+		rec = &recorder{ReadWriter: g.Catline}
+		catline := g.Catline
+		g.Catline = rec
+		defer func() { g.Catline = catline }()
+	}
+
 	for len(ls.Cmds) != 0 {
-		nextls, term, runner, err := g.ProcessPipeline(ls)
+		nextls, t, runner, err := g.ProcessPipeline(ls)
 		if err != nil {
 			return nil, nil, nil, err
 		}
 		ls = *nextls
-		pipeline = append(pipeline, piperun{f: runner, t: *term})
+		term = *t
+		pipeline = append(pipeline, piperun{f: runner, t: term})
 		if term.String() != "&&" && term.String() != "||" {
 			break
 		}
 	}
 
+	if term.String() == "&" {
+		var text string
+		if rec == nil || len(rec.lines) == 0 {
+			text = strings.Join(quoted[:len(before)-len(ls.Cmds)],
+				" ")
+		} else if len(ls.Cmds) == 0 {
+			text = strings.Join(quoted, " ") + "\n" +
+				strings.Join(rec.lines, "\n")
+		} else {
+			return nil, nil, nil,
+				errors.New("&: unexpected text after block")
+		}
+		text = strings.TrimSuffix(strings.TrimSpace(text), "&")
+		listfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+			return g.background(strings.TrimSpace(text),
+				stdin, stdout, stderr)
+		}
+		return &ls, &term, listfun, nil
+	}
+
 	listfun, err := g.MakeListFunc(pipeline)
 
 	return &ls, &term, listfun, err
 }
 
+// MakeListFunc returns a function that runs each pipeline of an and-or list
+// as conditioned by its terminator and the status of the previous. Errors
+// other than those that unwind are reported on stderr rather than returned;
+// the failure of the last pipeline run returns an ExitError with ErrExit,
+// as does its error with ErrAbort.
 func (g *Goes) MakeListFunc(pipeline []piperun) (func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error, error) {
 	listfun := func(stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
-		var err error
+		ranLast := false
 		skipNext := false
-		for _, runfun := range pipeline {
-			term := runfun.t
+		for i, runfun := range pipeline {
+			term := runfun.t.String()
 			if !skipNext {
-				err = runfun.f(stdin, stdout, stderr)
-				if err != nil {
-					g.Status = err
-				}
-				skipNext = false
-			}
-			if g.Status != nil {
-				if term.String() == "&&" {
-					skipNext = true
+				err := runfun.f(stdin, stdout, stderr)
+				if IsUnwinding(err) {
+					return err
 				}
-			} else {
-				if term.String() == "||" {
-					skipNext = true
-
+				ranLast = i == len(pipeline)-1
+				var status StatusError
+				if err != nil && !errors.As(err, &status) {
+					if ranLast && (g.ErrExit || g.ErrAbort) {
+						return &ExitError{err}
+					}
+					fmt.Fprintln(stderr, err)
 				}
 			}
+			skipNext = (term == "&&" && g.Status != nil) ||
+				(term == "||" && g.Status == nil)
 		}
-		return err
+		if ranLast && g.ErrExit && g.Status != nil {
+			return &ExitError{g.Status}
+		}
+		return nil
 	}
 	return listfun, nil
 }
@@ -0,0 +1,229 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// isGroup reports whether the command line starts a subshell, ( list ),
+// or brace group, { list; }.
+func isGroup(cl shellutils.Cmdline) bool {
+	name := cl.Cmds[0].String()
+	return name == "(" || name == "{"
+}
+
+// Group is the Blocker of a subshell or brace group at the start of the
+// list. It returns the list starting with the closing ) or } command line
+// followed by any redirections of the whole group.
+func (g *Goes) Group(ls shellutils.List) (*shellutils.List, func(io.Reader, io.Writer, io.Writer) error, error) {
+	if ls.Cmds[0].Cmds[0].String() == "(" {
+		return g.subshell(ls)
+	}
+	return g.braceGroup(ls)
+}
+
+// moreList returns the list, reading the next from Catline if it's empty.
+func (g *Goes) moreList(prompt string, ls shellutils.List) (shellutils.List, error) {
+	for len(ls.Cmds) == 0 {
+		newls, err := shellutils.Parse(prompt, g.Catline)
+		if err != nil {
+			return ls, err
+		}
+		ls = *newls
+	}
+	return ls, nil
+}
+
+// dropWord removes the first word of the list, and its command line if
+// that was the only word.
+func dropWord(ls shellutils.List) shellutils.List {
+	cl := ls.Cmds[0]
+	if len(cl.Cmds) > 1 {
+		cl.Cmds = cl.Cmds[1:]
+		ls.Cmds = append([]shellutils.Cmdline{cl}, ls.Cmds[1:]...)
+	} else {
+		ls.Cmds = ls.Cmds[1:]
+	}
+	return ls
+}
+
+// braceGroup runs the list in the current context, so that its variables,
+// functions and working directory persist.
+func (g *Goes) braceGroup(ls shellutils.List) (*shellutils.List, func(io.Reader, io.Writer, io.Writer) error, error) {
+	var body []func(io.Reader, io.Writer, io.Writer) error
+	ls = dropWord(ls)
+	for {
+		var err error
+		ls, err = g.moreList("{>", ls)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ls.Cmds[0].Cmds[0].String() == "}" {
+			break
+		}
+		nextls, _, runfun, err := g.ProcessList(ls)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = append(body, runfun)
+		ls = *nextls
+	}
+	end := ls.Cmds[0]
+	runfun := func(stdin io.Reader, stdout, stderr io.Writer) error {
+		var closers []io.Closer
+		defer func() {
+			for _, c := range closers {
+				c.Close()
+			}
+		}()
+		in, out, errw, err := g.groupRedirect(end, stdin, stdout,
+			stderr, &closers)
+		if err != nil {
+			return err
+		}
+		for _, f := range body {
+			if err := f(in, out, errw); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return &ls, runfun, nil
+}
+
+// groupRedirect returns the group's stdio after the redirections that
+// follow its closing word.
+func (g *Goes) groupRedirect(end shellutils.Cmdline, stdin io.Reader, stdout, stderr io.Writer, closers *[]io.Closer) (io.Reader, io.Writer, io.Writer, error) {
+	_, args := end.SliceSubst(g.Getenv, g.Cmdsubst)
+	args, in, out, errw, err := g.redirect(args, stdin, stdout, stderr,
+		closers)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(args) > 1 {
+		return nil, nil, nil, fmt.Errorf("%s: unexpected %s", args[0],
+			strings.Join(args[1:], " "))
+	}
+	return in, out, errw, nil
+}
+
+// subshell runs the list source text in a forked cli with a copy of the
+// context variables, so that its changes to these and the working
+// directory don't persist. The subshell also has the functions, see
+// FunctionsEnv.
+func (g *Goes) subshell(ls shellutils.List) (*shellutils.List, func(io.Reader, io.Writer, io.Writer) error, error) {
+	var (
+		text  string
+		sep   string
+		end   shellutils.Cmdline
+		stack []string
+	)
+	ls = dropWord(ls)
+	stack = append(stack, "(")
+	for len(stack) > 0 {
+		var err error
+		ls, err = g.moreList("(>", ls)
+		if err != nil {
+			return nil, nil, err
+		}
+		cl := ls.Cmds[0]
+		ls.Cmds = ls.Cmds[1:]
+		for i, w := range cl.Cmds {
+			switch s := w.String(); {
+			case i == 0 && (s == "(" || s == "case"):
+				stack = append(stack, s)
+			case i == 0 && s == "esac" && stack[len(stack)-1] == "case":
+				stack = stack[:len(stack)-1]
+			case s == ")" && stack[len(stack)-1] == "(":
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				end = shellutils.Cmdline{
+					Cmds: cl.Cmds[i:],
+					Term: cl.Term,
+				}
+				cl = shellutils.Cmdline{Cmds: cl.Cmds[:i]}
+				break
+			}
+		}
+		if len(cl.Cmds) > 0 {
+			text += sep + cl.Quote()
+			sep = "\n"
+			if len(cl.Term.String()) > 0 {
+				sep = " "
+			}
+		}
+	}
+	if len(strings.TrimSpace(text)) == 0 {
+		return nil, nil, errors.New("(: empty subshell")
+	}
+	if g.linter != nil {
+		g.linter.subshell(g, text)
+	}
+	ls.Cmds = append([]shellutils.Cmdline{end}, ls.Cmds...)
+	runfun := func(stdin io.Reader, stdout, stderr io.Writer) error {
+		if _, found := g.ByName["cli"]; !found {
+			return errors.New("(: has no cli")
+		}
+		var (
+			closers []io.Closer
+			cl      shellutils.Cmdline
+		)
+		defer func() {
+			for _, c := range closers {
+				c.Close()
+			}
+		}()
+		in, out, errw, err := g.groupRedirect(end, stdin, stdout,
+			stderr, &closers)
+		if err != nil {
+			return err
+		}
+		for k, v := range g.EnvMap {
+			cl.Cmds = append(cl.Cmds, shellutils.Word{
+				Tokens: []shellutils.Token{
+					{V: k, T: shellutils.TokenLiteral},
+					{V: "=", T: shellutils.TokenEnvset},
+					{V: v, T: shellutils.TokenLiteral},
+				},
+			})
+		}
+		if s, found := g.functionsEnv(); found {
+			cl.Cmds = append(cl.Cmds, shellutils.Word{
+				Tokens: []shellutils.Token{
+					{V: FunctionsEnv, T: shellutils.TokenLiteral},
+					{V: "=", T: shellutils.TokenEnvset},
+					{V: s, T: shellutils.TokenLiteral},
+				},
+			})
+		}
+		args := []string{"cli"}
+		if g.ErrExit {
+			args = append(args, "-e")
+		}
+		if !g.ErrAbort {
+			args = append(args, "-f")
+		}
+		for _, arg := range append(args, "-c", text) {
+			cl.Cmds = append(cl.Cmds, shellutils.Word{
+				Tokens: []shellutils.Token{
+					{V: arg, T: shellutils.TokenLiteral},
+				},
+			})
+		}
+		f, err := g.ProcessCommand(cl, &closers)
+		if err != nil {
+			return err
+		}
+		return f(in, out, errw)
+	}
+	return &ls, runfun, nil
+}
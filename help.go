@@ -14,14 +14,14 @@ func (g *Goes) Help(args ...string) string {
 	g.swap(args)
 	g.shift(args)
 	if len(args) > 0 {
-		if v, found := g.ByName[args[0]]; found {
+		if v, found := g.lookup(args[0]); found {
 			if method, found := v.(helper); found {
 				return method.Help(args[1:]...)
 			}
-			return Usage(v)
+			return Usage(catalogUsage(g.usageOf(args[0], v)))
 		}
 	}
-	return Usage(g)
+	return Usage(catalogUsage(g.usageOf("", g)))
 }
 
 func (g *Goes) help(args ...string) error {
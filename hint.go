@@ -0,0 +1,102 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/external/options"
+)
+
+// A Hint is a candidate for the next word of a command line with its
+// apropos; or, for the value of a parameter, its placeholder and help.
+type Hint struct {
+	Word    string
+	Apropos string
+}
+
+// Hints returns the candidates for the last of the given words, which is
+// empty for those of the next word. The words of nested goes, such as
+// "ip route", are hinted by the goes of the command; the options of other
+// commands by their Optioner, with the values of a parameter other than a
+// file, or, failing that, their completions.
+// Commands without either are hinted with their usage.
+func (g *Goes) Hints(args ...string) (hints []Hint) {
+	n := len(args)
+	if n == 0 {
+		args, n = []string{""}, 1
+	}
+	if n == 1 {
+		g.loadPlugins()
+		for _, name := range g.Names() {
+			if len(name) > 0 && strings.HasPrefix(name, args[0]) {
+				v, _ := g.lookup(name)
+				hints = append(hints, Hint{name,
+					g.aproposOf(name, v).String()})
+			}
+		}
+		for builtin := range g.Builtins() {
+			if strings.HasPrefix(builtin, args[0]) {
+				hints = append(hints, Hint{builtin, "helper"})
+			}
+		}
+		sort.Slice(hints, func(i, j int) bool {
+			return hints[i].Word < hints[j].Word
+		})
+		return
+	}
+	if _, found := g.Builtins()[args[0]]; found {
+		return g.Hints(args[n-1])
+	}
+	v, found := g.lookup(args[0])
+	if !found {
+		words := append([]string{}, args[:n-1]...)
+		if !g.shift(words) {
+			return
+		}
+		args = append(words, args[n-1])
+		if v, found = g.lookup(args[0]); !found {
+			return
+		}
+	}
+	switch t := v.(type) {
+	case *Goes:
+		return t.Hints(args[1:]...)
+	case cmd.Optioner:
+		opts, isValue := t.Options().Hints(args[1:]...)
+		for _, o := range opts {
+			word := strings.Join(append([]string{o.Name},
+				o.Aliases...), ", ")
+			if isValue {
+				word = o.ArgName()
+			} else if !o.IsBool() {
+				word += " " + o.ArgName()
+			}
+			hints = append(hints, Hint{word, o.Help})
+			if isValue && o.Type != options.File {
+				for _, s := range t.Options().Complete(args[1:]...) {
+					hints = append(hints, Hint{Word: s})
+				}
+			}
+		}
+		if len(opts) > 0 {
+			return
+		}
+	case completer:
+		for _, s := range t.Complete(args[1:]...) {
+			hints = append(hints, Hint{Word: s})
+		}
+		if len(hints) > 0 {
+			return
+		}
+	}
+	if u, found := v.(Usager); found {
+		hints = append(hints, Hint{Word: strings.TrimSpace(
+			g.usageOf(args[0], u))})
+	}
+	return
+}
@@ -0,0 +1,41 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"strings"
+
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+// CatalogPath returns the path of the named command in the message
+// catalogs of package lang, e.g. "ip route". That of the top goes itself
+// is "".
+func (g *Goes) CatalogPath(name string) string {
+	var words []string
+	if path := g.Path(); len(path) > 0 {
+		// without the top goes
+		words = append(words, path[1:]...)
+	}
+	if len(name) > 0 {
+		words = append(words, name)
+	}
+	return strings.Join(words, " ")
+}
+
+// aproposOf returns the apropos of the named command with that of the
+// preferred language's catalog.
+func (g *Goes) aproposOf(name string, v cmd.Cmd) lang.Alt {
+	return v.Apropos().Merge(g.CatalogPath(name), "apropos")
+}
+
+func (g *Goes) usageOf(name string, v Usager) string {
+	return lang.MergeString(v.Usage(), g.CatalogPath(name), "usage")
+}
+
+func (g *Goes) manOf(name string, v maner) lang.Alt {
+	return v.Man().Merge(g.CatalogPath(name), "man")
+}
@@ -0,0 +1,227 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// Package cgroup makes and accounts the cgroup v2 groups of goes, each a
+// child of Root/Parent, e.g. /sys/fs/cgroup/goes/vnetd
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Root is the mount of the cgroup v2 hierarchy.
+var Root = "/sys/fs/cgroup"
+
+// Parent is the group of those made by goes.
+const Parent = "goes"
+
+// Controllers are those enabled for the groups of goes.
+var Controllers = []string{"cpu", "memory", "pids"}
+
+// A Cgroup is the directory of a group.
+type Cgroup string
+
+// Stat is the accounting of a group; a zero max is unlimited.
+type Stat struct {
+	Path      string        `json:"path"`
+	Memory    uint64        `json:"memory"`
+	MemoryMax uint64        `json:"memory_max"`
+	CPU       time.Duration `json:"cpu"`
+	Pids      uint64        `json:"pids"`
+	PidsMax   uint64        `json:"pids_max"`
+	OOMKills  uint64        `json:"oom_kills"`
+}
+
+// New makes, if necessary, the named group of goes with its Controllers.
+func New(name string) (Cgroup, error) {
+	if len(name) == 0 || strings.ContainsRune(name, '/') ||
+		name == "." || name == ".." {
+		return "", fmt.Errorf("%q: invalid cgroup", name)
+	}
+	if _, err := os.Stat(filepath.Join(Root, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("%s: cgroup v2: not mounted", Root)
+	}
+	parent := Cgroup(filepath.Join(Root, Parent))
+	if err := os.MkdirAll(string(parent), 0755); err != nil {
+		return "", err
+	}
+	if err := Cgroup(Root).enable(); err != nil {
+		return "", err
+	}
+	if err := parent.enable(); err != nil {
+		return "", err
+	}
+	cg := Cgroup(filepath.Join(string(parent), name))
+	if err := os.Mkdir(string(cg), 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+	return cg, nil
+}
+
+// Of returns the group of goes that has the process, if any.
+func Of(pid int) (Cgroup, bool) {
+	b, err := ioutil.ReadFile(fmt.Sprint("/proc/", pid, "/cgroup"))
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if !strings.HasPrefix(line, "0::/"+Parent+"/") {
+			continue
+		}
+		return Cgroup(filepath.Join(Root, line[len("0::"):])), true
+	}
+	return "", false
+}
+
+func (cg Cgroup) String() string { return string(cg) }
+
+// enable the Controllers of the children of the group that are available.
+func (cg Cgroup) enable() error {
+	b, err := ioutil.ReadFile(filepath.Join(string(cg),
+		"cgroup.controllers"))
+	if err != nil {
+		return err
+	}
+	available := strings.Fields(string(b))
+	var enable []string
+	for _, c := range Controllers {
+		for _, a := range available {
+			if c == a {
+				enable = append(enable, "+"+c)
+			}
+		}
+	}
+	if len(enable) == 0 {
+		return nil
+	}
+	return cg.Set("cgroup.subtree_control", strings.Join(enable, " "))
+}
+
+// Set the value of the group's file, e.g. memory.max
+func (cg Cgroup) Set(file, value string) error {
+	return ioutil.WriteFile(filepath.Join(string(cg), file),
+		[]byte(value), 0644)
+}
+
+// Get the value of the group's file.
+func (cg Cgroup) Get(file string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(string(cg), file))
+	return strings.TrimSpace(string(b)), err
+}
+
+// Add the process to the group.
+func (cg Cgroup) Add(pid int) error {
+	return cg.Set("cgroup.procs", strconv.Itoa(pid))
+}
+
+// cantCloneInto is set once the kernel has failed to clone into a group.
+var cantCloneInto int32
+
+// Start the command in the group. It's cloned into the group, so it and
+// any children are in the group from the first, unless the kernel can't,
+// i.e. before Linux 5.7 or where clone3 is filtered. Then the command is
+// added once started, so any children it forks before then are not.
+func (cg Cgroup) Start(x *exec.Cmd) error {
+	if atomic.LoadInt32(&cantCloneInto) == 0 {
+		f, err := os.Open(string(cg))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		attr, saved := x.SysProcAttr, *x
+		into := syscall.SysProcAttr{}
+		if attr != nil {
+			into = *attr
+		}
+		into.UseCgroupFD = true
+		into.CgroupFD = int(f.Fd())
+		x.SysProcAttr = &into
+		err = x.Start()
+		if !errors.Is(err, syscall.ENOSYS) &&
+			!errors.Is(err, syscall.EINVAL) {
+			return err
+		}
+		atomic.StoreInt32(&cantCloneInto, 1)
+		// the command wasn't started, so it may be again
+		*x = saved
+		x.SysProcAttr = attr
+	}
+	if err := x.Start(); err != nil {
+		return err
+	}
+	if err := cg.Add(x.Process.Pid); err != nil {
+		x.Process.Kill()
+		x.Wait()
+		return err
+	}
+	return nil
+}
+
+// Events returns the counts of the group's events file, e.g. those of
+// memory.events
+//
+//	low 0
+//	high 0
+//	max 12
+//	oom 1
+//	oom_kill 1
+func (cg Cgroup) Events(file string) (map[string]uint64, error) {
+	f, err := os.Open(filepath.Join(string(cg), file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	events := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[1], 10, 64)
+		if err == nil {
+			events[fields[0]] = n
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Stat returns the accounting of the group of those controllers that are
+// enabled.
+func (cg Cgroup) Stat() Stat {
+	st := Stat{Path: strings.TrimPrefix(string(cg), Root)}
+	st.Memory = cg.uint("memory.current")
+	st.MemoryMax = cg.uint("memory.max")
+	st.Pids = cg.uint("pids.current")
+	st.PidsMax = cg.uint("pids.max")
+	if events, err := cg.Events("memory.events"); err == nil {
+		st.OOMKills = events["oom_kill"]
+	}
+	if cpu, err := cg.Events("cpu.stat"); err == nil {
+		st.CPU = time.Duration(cpu["usage_usec"]) * time.Microsecond
+	}
+	return st
+}
+
+// uint returns the value of the group's file or zero if it's "max" or
+// unavailable.
+func (cg Cgroup) uint(file string) uint64 {
+	s, err := cg.Get(file)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseUint(s, 10, 64)
+	return n
+}
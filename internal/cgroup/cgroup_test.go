@@ -0,0 +1,45 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cg := Cgroup(dir)
+	for file, value := range map[string]string{
+		"memory.current": "1048576\n",
+		"memory.max":     "max\n",
+		"memory.events":  "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n",
+		"pids.current":   "4\n",
+		"pids.max":       "64\n",
+		"cpu.stat":       "usage_usec 1500000\nuser_usec 1000000\n",
+	} {
+		err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value),
+			0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	st := cg.Stat()
+	if st.Memory != 1<<20 || st.MemoryMax != 0 || st.Pids != 4 ||
+		st.PidsMax != 64 || st.OOMKills != 1 ||
+		st.CPU != 1500*time.Millisecond {
+		t.Errorf("wrong: %+v", st)
+	}
+	if _, err := New("a/b"); err == nil {
+		t.Error("a/b: no error")
+	}
+}
@@ -11,19 +11,29 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 var Install = "/usr/bin/goes"
-var base, name, path string
+
+var (
+	mutex            sync.Mutex
+	base, name, path string
+)
 
 func Base() string {
+	n := Name()
+	mutex.Lock()
+	defer mutex.Unlock()
 	if len(base) == 0 {
-		base = filepath.Base(Name())
+		base = filepath.Base(n)
 	}
 	return base
 }
 
 func Name() string {
+	mutex.Lock()
+	defer mutex.Unlock()
 	if len(name) == 0 {
 		a := os.Args[0]
 		if strings.HasSuffix(a, ".test") {
@@ -46,9 +56,12 @@ func Command(args ...string) (cmd *exec.Cmd) {
 }
 
 func Path() string {
+	n := Name()
+	mutex.Lock()
+	defer mutex.Unlock()
 	if len(path) == 0 {
 		path = "/bin:/usr/bin"
-		dir := filepath.Dir(Name())
+		dir := filepath.Dir(n)
 		if dir != "/bin" && dir != "/usr/bin" {
 			path += ":" + dir
 		}
@@ -4,11 +4,6 @@
 
 package shellutils
 
-import (
-	"fmt"
-	"path/filepath"
-)
-
 // Cmdline is a slice of Words which may be variable setting, a command,
 // or arguments to that command. There is a seperate terminator which
 // is either a pipeline operator (|) or a list operator (; & || &&).
@@ -17,6 +12,21 @@ type Cmdline struct {
 	Term Word
 }
 
+// Quote returns the command line and its terminator as source text.
+func (c *Cmdline) Quote() string {
+	s := ""
+	for i, w := range c.Cmds {
+		if i > 0 {
+			s += " "
+		}
+		s += w.Quote()
+	}
+	if term := c.Term.String(); len(term) > 0 {
+		s += " " + term
+	}
+	return s
+}
+
 func (c *Cmdline) add(w *Word) {
 	if c.Cmds == nil {
 		c.Cmds = make([]Word, 0)
@@ -29,52 +39,23 @@ func (c *Cmdline) add(w *Word) {
 // map of the environment variables declared in the command,
 // and a slice of the command and its arguments as strings
 func (c *Cmdline) Slice(getenv func(string) string) (map[string]string, []string) {
+	return c.SliceSubst(getenv, nil)
+}
+
+// SliceSubst is Slice that also runs command substitutions with cmdsubst,
+// which returns the output of the given command text.
+func (c *Cmdline) SliceSubst(getenv func(string) string, cmdsubst func(string) string) (map[string]string, []string) {
 	envmap := make(map[string]string)
 	Cmdline := make([]string, 0)
 
 	for _, w := range c.Cmds {
-		s := ""
-		isEnvset := false
-		envsetOffset := 0
-		for _, t := range w.Tokens {
-			switch t.T {
-			case TokenLiteral:
-				s += t.V
-			case TokenEnvget:
-				s += getenv(t.V)
-			case TokenEnvset:
-				if !isEnvset {
-					isEnvset = true
-					envsetOffset = len(s)
-				}
-				s += t.V
-			case TokenGlob:
-				match, err := filepath.Glob(t.V)
-				if match == nil || err != nil {
-					s += t.V
-					continue
-				}
-				s += match[0]
-				if len(match) == 1 {
-					continue
-				}
-				Cmdline = append(Cmdline, s)
-				match = match[1:]
-				if len(match) > 1 {
-					Cmdline = append(Cmdline,
-						match[:len(match)-1]...)
-					match = match[len(match)-1:]
-				}
-				s = match[0]
-			default:
-				panic(fmt.Errorf("Unknown Token %v", t))
+		if len(Cmdline) == 0 {
+			if k, v, ok := w.assignment(getenv, cmdsubst); ok {
+				envmap[k] = v
+				continue
 			}
 		}
-		if len(Cmdline) == 0 && isEnvset && envsetOffset != 0 {
-			envmap[s[0:envsetOffset]] = s[envsetOffset+1:]
-		} else {
-			Cmdline = append(Cmdline, s)
-		}
+		Cmdline = append(Cmdline, w.Fields(getenv, cmdsubst)...)
 	}
 	return envmap, Cmdline
 }
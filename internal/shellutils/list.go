@@ -10,6 +10,18 @@ type List struct {
 	Cmds []Cmdline
 }
 
+// Quote returns the list as source text.
+func (ls *List) Quote() string {
+	s := ""
+	for i, cl := range ls.Cmds {
+		if i > 0 {
+			s += " "
+		}
+		s += cl.Quote()
+	}
+	return s
+}
+
 func (ls *List) add(cl *Cmdline) {
 	if ls.Cmds == nil {
 		ls.Cmds = make([]Cmdline, 0)
@@ -11,7 +11,10 @@ import (
 	"unicode/utf8"
 )
 
-var ErrMissingEndQuote = errors.New("Unexpected EOF while looking for matching quote")
+var (
+	ErrMissingEndQuote = errors.New("Unexpected EOF while looking for matching quote")
+	ErrMissingEndParen = errors.New("Unexpected EOF while looking for matching `)'")
+)
 
 func srcin(i io.ReadWriter, prompt string) (s string, err error) {
 	i.Write([]byte(prompt))
@@ -56,19 +59,36 @@ processRune:
 				continue
 			}
 
-			if strings.ContainsRune("|&;()<>", r) {
+			if strings.ContainsRune("|&;()<>", r) &&
+				!(r == '>' && w.isFd()) {
 				c.add(&w)
 			}
 		}
 
+		// Check for &> or &>>
+		if r == '&' && len(s) >= 1 && s[0] == '>' {
+			s = s[1:]
+			w.addLiteral("&>")
+			if len(s) >= 1 && s[0] == '>' {
+				s = s[1:]
+				w.addLiteral(">")
+			}
+			c.add(&w)
+			inWS = true
+			continue
+		}
+
 		if strings.ContainsRune("&;()<", r) {
 			w.addLiteral(string(r))
-			// hack - we know these are single-byte runes
-			if len(s) >= 1 && s[0] == byte(r) {
+			// hack - we know these are single-byte runes; each
+			// parenthesis is a word of its own, e.g. of
+			// nested subshells
+			if len(s) >= 1 && s[0] == byte(r) && r != '(' && r != ')' {
 				s = s[1:]
 				w.addLiteral(string(r))
 			}
-			if w.String() == ";" || w.String() == "&&" ||
+			if w.String() == ";" || w.String() == ";;" ||
+				w.String() == "&" || w.String() == "&&" ||
 				w.String() == "||" {
 				c.Term = w
 				w = Word{}
@@ -107,7 +127,19 @@ processRune:
 		}
 
 		if r == '$' && len(s) > 0 {
-			s, err = w.parseEnv(s)
+			if s[0] == '(' {
+				s, err = w.parseCmdsubst(s[1:], i, TokenCmdsubst)
+			} else {
+				s, err = w.parseEnv(s, TokenEnvget)
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if r == '`' {
+			s, err = w.parseBackquote(s, i, TokenCmdsubst)
 			if err != nil {
 				return nil, err
 			}
@@ -116,7 +148,15 @@ processRune:
 
 		if r == '>' {
 			w.addLiteral(">")
-			if len(s) >= 1 && s[0] == '>' {
+			if len(s) >= 1 && s[0] == '&' {
+				// >&N duplicates file descriptor N
+				s = s[1:]
+				w.addLiteral("&")
+				for len(s) >= 1 && s[0] >= '0' && s[0] <= '9' {
+					w.addLiteral(s[:1])
+					s = s[1:]
+				}
+			} else if len(s) >= 1 && s[0] == '>' {
 				s = s[1:]
 				w.addLiteral(">")
 				if len(s) >= 1 && s[0] == '>' {
@@ -163,7 +203,21 @@ processRune:
 					}
 
 					if r == '$' && len(s) > 0 {
-						s, err = w.parseEnv(s)
+						if s[0] == '(' {
+							s, err = w.parseCmdsubst(s[1:], i,
+								TokenQuotedCmdsubst)
+						} else {
+							s, err = w.parseEnv(s,
+								TokenQuotedEnvget)
+						}
+						if err != nil {
+							return nil, err
+						}
+						continue
+					}
+					if r == '`' {
+						s, err = w.parseBackquote(s, i,
+							TokenQuotedCmdsubst)
 						if err != nil {
 							return nil, err
 						}
@@ -181,7 +235,8 @@ processRune:
 							continue
 						}
 						r1, wid := utf8.DecodeRuneInString(s)
-						if r1 == '$' || r1 == '"' || r1 == '\\' {
+						if r1 == '$' || r1 == '"' || r1 == '\\' ||
+							r1 == '`' {
 							r = r1
 							s = s[wid:]
 						}
@@ -0,0 +1,94 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Pattern returns the word as a pattern for Match. Variables and command
+// substitutions are expanded but not split; quoted text matches literally.
+func (w *Word) Pattern(getenv func(string) string, cmdsubst func(string) string) string {
+	s := ""
+	for _, t := range w.Tokens {
+		switch t.T {
+		case TokenLiteral, TokenEnvset:
+			s += escapePattern(t.V)
+		case TokenEnvget, TokenQuotedEnvget:
+			s += getenv(t.V)
+		case TokenCmdsubst, TokenQuotedCmdsubst:
+			if cmdsubst != nil {
+				s += cmdsubst(t.V)
+			}
+		default:
+			s += t.V
+		}
+	}
+	return s
+}
+
+func escapePattern(s string) string {
+	r := ""
+	for _, c := range s {
+		if strings.ContainsRune(`*?[\`, c) {
+			r += `\`
+		}
+		r += string(c)
+	}
+	return r
+}
+
+// Match reports whether s matches the shell pattern where '*' is any
+// string, including '/' unlike filepath.Match; '?' is any character;
+// '[...]' is any of the enclosed characters or ranges or, with a leading
+// '!' or '^', any other character; and '\c' is the character c. The
+// characters are runes, not bytes, e.g. '?' matches "é".
+func Match(pattern, s string) (bool, error) {
+	re := "(?s)^"
+	p := []rune(pattern)
+	for i := 0; i < len(p); i++ {
+		switch p[i] {
+		case '*':
+			re += ".*"
+		case '?':
+			re += "."
+		case '\\':
+			if i+1 < len(p) {
+				i++
+			}
+			re += regexp.QuoteMeta(string(p[i]))
+		case '[':
+			end := indexRune(p[i+1:], ']')
+			if end == 0 {
+				// a leading ']' is enclosed
+				end = indexRune(p[i+2:], ']') + 1
+			}
+			if end <= 0 {
+				re += `\[`
+				continue
+			}
+			class := string(p[i+1 : i+1+end])
+			i += end + 1
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			re += "[" + strings.Replace(class, `\`, `\\`, -1) + "]"
+		default:
+			re += regexp.QuoteMeta(string(p[i]))
+		}
+	}
+	return regexp.MatchString(re+"$", s)
+}
+
+// indexRune returns the index of the first r in p, or -1 if none.
+func indexRune(p []rune, r rune) int {
+	for i, c := range p {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
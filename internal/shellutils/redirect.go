@@ -0,0 +1,93 @@
+// Copyright © 2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package shellutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Redirection is a numbered file descriptor redirection of a command.
+// Fd is 1 for stdout, 2 for stderr, or 0 for both with &> and &>>. A
+// redirection either duplicates another descriptor, Dup, or creates or
+// appends to URL.
+type Redirection struct {
+	Fd     int
+	Dup    int
+	Append bool
+	URL    string
+}
+
+func (r Redirection) String() string {
+	s := fmt.Sprint(r.Fd, ">")
+	if r.Fd == 0 {
+		s = "&>"
+	}
+	if r.Dup != 0 {
+		return fmt.Sprint(s, "&", r.Dup)
+	}
+	if r.Append {
+		s += ">"
+	}
+	return s + " " + r.URL
+}
+
+// Redirections removes and returns, in order, these redirections from the
+// command arguments.
+//
+//	N> URL	N>> URL	N>&M	>&M	&> URL	&>> URL
+//
+// Where N and M are either 1 for stdout or 2 for stderr. The stdout
+// redirections without a number, > and >>, remain in the arguments.
+func Redirections(args []string) ([]Redirection, []string, error) {
+	var redirs []Redirection
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		op := strings.TrimLeft(arg, "0123456789")
+		num := arg[:len(arg)-len(op)]
+		r := Redirection{Fd: 1}
+		switch {
+		case op == ">" || op == ">>":
+			if len(num) == 0 {
+				rest = append(rest, arg)
+				continue
+			}
+		case op == "&>" || op == "&>>":
+			if len(num) != 0 {
+				return nil, nil, fmt.Errorf("%s: unexpected", arg)
+			}
+			r.Fd = 0
+		case op == ">&1" || op == ">&2":
+		case strings.HasPrefix(op, ">&"):
+			return nil, nil, fmt.Errorf("%s: unsupported redirection", arg)
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		if len(num) != 0 {
+			if num != "1" && num != "2" {
+				return nil, nil, fmt.Errorf("%s: unsupported file descriptor", arg)
+			}
+			r.Fd = int(num[0] - '0')
+		}
+		switch op {
+		case ">&1", ">&2":
+			r.Dup = int(op[2] - '0')
+			if r.Dup != r.Fd {
+				redirs = append(redirs, r)
+			}
+			continue
+		case ">>", "&>>":
+			r.Append = true
+		}
+		if i++; i == len(args) {
+			return nil, nil, fmt.Errorf("%s: missing URL", arg)
+		}
+		r.URL = args[i]
+		redirs = append(redirs, r)
+	}
+	return redirs, rest, nil
+}
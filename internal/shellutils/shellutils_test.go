@@ -110,6 +110,24 @@ func TestBoolean(t *testing.T) {
 	cmd.print()
 }
 
+func TestNestedSubshell(t *testing.T) {
+	ls, err := testSlice([]string{"((echo a; (echo b)))"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var words []string
+	for _, cl := range ls.Cmds {
+		for _, w := range cl.Cmds {
+			words = append(words, w.String())
+		}
+	}
+	want := "( ( echo a ( echo b ) ) )"
+	if got := strings.Join(words, " "); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 // TestDoublequote: The backslash retains its special meaning only when followed by one of the following characters:
 // ‘$’, ‘`’, ‘"’, ‘\’, or newline.
 func TestDoublequote(t *testing.T) {
@@ -123,3 +141,212 @@ func TestDoublequote(t *testing.T) {
 
 	cmd.print()
 }
+
+func TestCmdsubst(t *testing.T) {
+	script := []string{`echo a$(echo "b c" $(true))d "x$(echo b  c)y" ` +
+		"`echo q` X=$(echo 1 2)"}
+	subst := func(s string) string {
+		switch s {
+		case `echo "b c" $(true)`:
+			return "b c\n"
+		case "echo b  c":
+			return "b  c"
+		case "echo q":
+			return "q"
+		case "echo 1 2":
+			return "1 2"
+		}
+		t.Errorf("unexpected substitution %q", s)
+		return ""
+	}
+	ls, err := testSlice(script)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	env, args := ls.Cmds[0].SliceSubst(os.Getenv, subst)
+	want := []string{"echo", "ab", "cd", "xb  cy", "q", "X=1", "2"}
+	if strings.Join(args, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", args, want)
+	}
+	if len(env) != 0 {
+		t.Errorf("unexpected environment %v", env)
+	}
+	ls, err = testSlice([]string{"X=$(echo 1 2)"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	env, args = ls.Cmds[0].SliceSubst(os.Getenv, subst)
+	if len(args) != 0 || env["X"] != "1 2" {
+		t.Errorf("got %v %q, want X=\"1 2\"", env, args)
+	}
+}
+
+func TestCmdsubstContinuation(t *testing.T) {
+	script := []string{"echo $(echo (", "x))"}
+	ls, err := testSlice(script)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if s := ls.Cmds[0].Cmds[1].String(); s != "$(echo (\nx))" {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestSpecialParameter(t *testing.T) {
+	ls, err := testSlice([]string{"echo $?x ${?}"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, args := ls.Cmds[0].Slice(func(k string) string {
+		if k == "?" {
+			return "1"
+		}
+		return ""
+	})
+	if got := strings.Join(args, " "); got != "echo 1x 1" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestBackground(t *testing.T) {
+	ls, err := testSlice([]string{
+		`sleep 1 && echo "a b" 'c$d' $HOME $(date) & echo $!`})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if n := len(ls.Cmds); n != 3 {
+		t.Fatalf("got %d command lines, want 3", n)
+	}
+	if term := ls.Cmds[1].Term.String(); term != "&" {
+		t.Errorf("got terminator %q", term)
+	}
+	want := `sleep 1 && echo 'a b' 'c$d' ${HOME} $(date) & echo $!`
+	if got := ls.Quote(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the quoted text must parse back to the same list
+	again, err := testSlice([]string{want})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got := again.Quote(); got != want {
+		t.Errorf("got %q after reparse", got)
+	}
+}
+
+func TestRedirections(t *testing.T) {
+	ls, err := testSlice([]string{
+		"cmd a2 2>err 2>> err >&2 1>&2 2>&1 &>all &>>all > out"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, args := ls.Cmds[0].Slice(os.Getenv)
+	redirs, args, err := Redirections(args)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got := strings.Join(args, " "); got != "cmd a2 > out" {
+		t.Errorf("got args %q", got)
+	}
+	var got []string
+	for _, r := range redirs {
+		got = append(got, r.String())
+	}
+	want := "2> err|2>> err|1>&2|1>&2|2>&1|&> all|&>> all"
+	if strings.Join(got, "|") != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, bad := range [][]string{
+		{"cmd", "3>", "x"},
+		{"cmd", "2>"},
+		{"cmd", ">&3"},
+	} {
+		if _, _, err := Redirections(bad); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "a/b", true},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"[a-c]x", "bx", true},
+		{"[!a-c]x", "bx", false},
+		{"[]]", "]", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{"eth*.1", "eth0.1", true},
+		{"[", "[", true},
+		{"?", "é", true},
+		{"??", "é", false},
+		{"[é]", "é", true},
+		{"[!é]", "é", false},
+		{"[à-ü]", "é", true},
+		{`\é?`, "éa", true},
+	} {
+		got, err := Match(tc.pattern, tc.s)
+		if err != nil {
+			t.Errorf("%q: %v", tc.pattern, err)
+		} else if got != tc.want {
+			t.Errorf("Match(%q, %q) = %v", tc.pattern, tc.s, got)
+		}
+	}
+}
+
+func TestCaseArms(t *testing.T) {
+	ls, err := testSlice([]string{
+		`case "$x" in a|'*') echo a;; *) echo b;; esac`})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var terms []string
+	for _, cl := range ls.Cmds {
+		terms = append(terms, cl.Term.String())
+	}
+	if got := strings.Join(terms, " "); got != "| ;; ;; " {
+		t.Errorf("got terminators %q", got)
+	}
+	p := ls.Cmds[1].Cmds[0].Pattern(os.Getenv, nil)
+	if p != `\*` {
+		t.Errorf("got pattern %q", p)
+	}
+}
+
+func TestPositionalParameters(t *testing.T) {
+	ls, err := testSlice([]string{`echo $# $10 ${10} "$@" x$@y $* "$*"`})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	params := []string{"a b", "c"}
+	_, args := ls.Cmds[0].Slice(func(k string) string {
+		switch k {
+		case "#":
+			return "2"
+		case "1", "2":
+			return params[k[0]-'1']
+		case "*":
+			return "a b c"
+		}
+		return ""
+	})
+	want := []string{"echo", "2", "a b0", "", "a b", "c", "xa", "b", "cy",
+		"a", "b", "c", "a b c"}
+	if strings.Join(args, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", args, want)
+	}
+}
@@ -12,6 +12,12 @@ package shellutils
 // tokenEnvset is the operator to set an environment variable. The string is
 // the assignment operator, i.e. =. This is represented as a token to prevent
 // quoted = characters to be interpreted as setting environment variables
+// tokenGlob is a file name pattern to be expanded to matching paths.
+// tokenCmdsubst is a command substitution, $(...) or `...`. The string is
+// the text of the command whose output replaces the token; the output is
+// split into fields. tokenQuotedCmdsubst is the same within double quotes,
+// where the output is not split. tokenQuotedEnvget is a variable within
+// double quotes, which differs from tokenEnvget only for $@ and $*.
 type Tokentype int
 
 const (
@@ -19,6 +25,9 @@ const (
 	TokenEnvget
 	TokenEnvset
 	TokenGlob
+	TokenCmdsubst
+	TokenQuotedCmdsubst
+	TokenQuotedEnvget
 )
 
 // Token is a type and a string value. During parsing, we convert
@@ -7,7 +7,9 @@ package shellutils
 import (
 	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -29,6 +31,16 @@ func (w *Word) add(s string, ty Tokentype) {
 	w.Tokens = append(w.Tokens, t)
 }
 
+// isFd reports whether the word is a file descriptor number that may
+// precede a redirection, e.g. 2>.
+func (w *Word) isFd() bool {
+	if len(w.Tokens) != 1 || w.Tokens[0].T != TokenLiteral {
+		return false
+	}
+	v := w.Tokens[0].V
+	return len(v) == 1 && v[0] >= '0' && v[0] <= '9'
+}
+
 // addLiteral is a helper routine to add literal text. It has the optimization
 // of concatenating successful calls to addLiteral. This is helpful because
 // addLiteral is mostly called rune by rune
@@ -43,7 +55,13 @@ func (w *Word) addLiteral(s string) {
 	w.add(s, TokenLiteral)
 }
 
-func (w *Word) parseEnv(s string) (string, error) {
+// specialParameters may follow $ without braces, e.g. $? is the exit
+// status of the last command, $! the process id of the last background
+// job, and $1 through $9, $#, $@ and $* the positional parameters. Others,
+// like ${10}, must be braced.
+const specialParameters = "?!#@*123456789"
+
+func (w *Word) parseEnv(s string, ty Tokentype) (string, error) {
 	envvar := ""
 	if s[0] == '{' {
 		s = s[1:]
@@ -51,7 +69,7 @@ func (w *Word) parseEnv(s string) (string, error) {
 			r, wid := utf8.DecodeRuneInString(s)
 			s = s[wid:]
 			if r == '}' {
-				w.add(envvar, TokenEnvget)
+				w.add(envvar, ty)
 				return s, nil
 			}
 			if unicode.IsSpace(r) || strings.ContainsRune("|&;()<>{'\"$/", r) {
@@ -62,6 +80,12 @@ func (w *Word) parseEnv(s string) (string, error) {
 		return "", errors.New("Unexpected end-of-line")
 	}
 
+	// special parameters are a single character
+	if strings.ContainsRune(specialParameters, rune(s[0])) {
+		w.add(s[:1], ty)
+		return s[1:], nil
+	}
+
 	for len(s) > 0 {
 		r, wid := utf8.DecodeRuneInString(s)
 		if unicode.IsSpace(r) || strings.ContainsRune("|&;()<>{}'\"$/", r) {
@@ -70,26 +94,285 @@ func (w *Word) parseEnv(s string) (string, error) {
 		s = s[wid:]
 		envvar += string(r)
 	}
-	w.add(envvar, TokenEnvget)
+	w.add(envvar, ty)
 	return s, nil
 }
 
+// parseCmdsubst collects the command text of a $(...) substitution up to
+// the matching close parenthesis, reading continuation lines as needed.
+// Nested parentheses, quotes and escapes are passed through verbatim for
+// the later parse of the command itself.
+func (w *Word) parseCmdsubst(s string, i io.ReadWriter, ty Tokentype) (string, error) {
+	var (
+		cmd   string
+		err   error
+		quote rune
+	)
+	depth := 1
+	for {
+		for len(s) > 0 {
+			r, wid := utf8.DecodeRuneInString(s)
+			s = s[wid:]
+			switch {
+			case r == '\\' && quote != '\'' && len(s) > 0:
+				r1, wid := utf8.DecodeRuneInString(s)
+				s = s[wid:]
+				cmd += string(r) + string(r1)
+				continue
+			case quote != 0:
+				if r == quote {
+					quote = 0
+				}
+			case r == '\'' || r == '"':
+				quote = r
+			case r == '(':
+				depth++
+			case r == ')':
+				depth--
+				if depth == 0 {
+					w.add(cmd, ty)
+					return s, nil
+				}
+			}
+			cmd += string(r)
+		}
+		cmd += "\n"
+		s, err = srcin(i, "> ")
+		if err != nil {
+			if err == io.EOF {
+				return "", ErrMissingEndParen
+			}
+			return "", err
+		}
+	}
+}
+
+// parseBackquote collects the command text of a `...` substitution. Within
+// backquotes, a backslash only escapes another backslash, a backquote or a
+// dollar sign.
+func (w *Word) parseBackquote(s string, i io.ReadWriter, ty Tokentype) (string, error) {
+	var (
+		cmd string
+		err error
+	)
+	for {
+		for len(s) > 0 {
+			r, wid := utf8.DecodeRuneInString(s)
+			s = s[wid:]
+			if r == '`' {
+				w.add(cmd, ty)
+				return s, nil
+			}
+			if r == '\\' && len(s) > 0 {
+				r1, wid := utf8.DecodeRuneInString(s)
+				if r1 == '`' || r1 == '\\' || r1 == '$' {
+					r = r1
+					s = s[wid:]
+				}
+			}
+			cmd += string(r)
+		}
+		cmd += "\n"
+		s, err = srcin(i, "> ")
+		if err != nil {
+			if err == io.EOF {
+				return "", ErrMissingEndQuote
+			}
+			return "", err
+		}
+	}
+}
+
 func (w *Word) String() string {
 	s := ""
 	for _, t := range w.Tokens {
-		s += t.V
+		switch t.T {
+		case TokenCmdsubst, TokenQuotedCmdsubst:
+			s += "$(" + t.V + ")"
+		default:
+			s += t.V
+		}
+	}
+	return s
+}
+
+// Quote returns the word as source text that parses back to the same
+// tokens.
+func (w *Word) Quote() string {
+	s := ""
+	for _, t := range w.Tokens {
+		switch t.T {
+		case TokenLiteral:
+			s += quote(t.V)
+		case TokenEnvget:
+			s += envget(t.V)
+		case TokenQuotedEnvget:
+			s += "\"" + envget(t.V) + "\""
+		case TokenCmdsubst:
+			s += "$(" + t.V + ")"
+		case TokenQuotedCmdsubst:
+			s += "\"$(" + t.V + ")\""
+		default:
+			s += t.V
+		}
 	}
 	return s
 }
 
+// envget returns the reference to the variable, which is braced unless a
+// special parameter.
+func envget(name string) string {
+	if len(name) == 1 && strings.Contains(specialParameters, name) {
+		return "$" + name
+	}
+	return "${" + name + "}"
+}
+
+// quote single quotes literal text if it has any special characters.
+func quote(s string) string {
+	if len(s) > 0 && !strings.ContainsAny(s,
+		" \t\n|&;()<>{}'\"$`\\*?[#=~") {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// assignment returns the variable name and value of a NAME=VALUE word.
+// Unlike Fields, the value isn't split or globbed.
+func (w *Word) assignment(getenv func(string) string, cmdsubst func(string) string) (name, value string, ok bool) {
+	for _, t := range w.Tokens {
+		if !ok {
+			switch t.T {
+			case TokenLiteral:
+				name += t.V
+			case TokenEnvset:
+				if len(name) == 0 {
+					return "", "", false
+				}
+				ok = true
+			default:
+				return "", "", false
+			}
+			continue
+		}
+		switch t.T {
+		case TokenEnvget, TokenQuotedEnvget:
+			value += getenv(t.V)
+		case TokenCmdsubst, TokenQuotedCmdsubst:
+			if cmdsubst != nil {
+				value += cmdsubst(t.V)
+			}
+		default:
+			value += t.V
+		}
+	}
+	return
+}
+
+// Fields converts a word into a slice of strings, looking up variables
+// with getenv, running command substitutions with cmdsubst, and expanding
+// globs. The output of an unquoted command substitution is split into
+// separate fields, as are the positional parameters of an unquoted $@ or $*
+// and each positional parameter of "$@"; a word made only of such
+// substitutions that produce no output is dropped altogether. A nil
+// cmdsubst substitutes nothing.
+func (w *Word) Fields(getenv func(string) string, cmdsubst func(string) string) (str []string) {
+	s := ""
+	vanish := len(w.Tokens) > 0
+	for _, t := range w.Tokens {
+		switch t.T {
+		case TokenLiteral, TokenEnvset:
+			s += t.V
+			vanish = false
+		case TokenEnvget:
+			if t.V != "@" && t.V != "*" {
+				s += getenv(t.V)
+				vanish = false
+				continue
+			}
+			// the positional parameters are split into fields
+			var f []string
+			n, _ := strconv.Atoi(getenv("#"))
+			for i := 1; i <= n; i++ {
+				f = append(f, strings.Fields(getenv(strconv.Itoa(i)))...)
+			}
+			if len(f) == 0 {
+				continue
+			}
+			vanish = false
+			s += f[0]
+			for _, field := range f[1:] {
+				str = append(str, s)
+				s = field
+			}
+		case TokenQuotedEnvget:
+			if t.V != "@" {
+				s += getenv(t.V)
+				vanish = false
+				continue
+			}
+			// each positional parameter is a separate field
+			n, _ := strconv.Atoi(getenv("#"))
+			for i := 1; i <= n; i++ {
+				if i > 1 {
+					str = append(str, s)
+					s = ""
+				}
+				s += getenv(strconv.Itoa(i))
+				vanish = false
+			}
+		case TokenQuotedCmdsubst:
+			if cmdsubst != nil {
+				s += cmdsubst(t.V)
+			}
+			vanish = false
+		case TokenCmdsubst:
+			if cmdsubst == nil {
+				continue
+			}
+			f := strings.Fields(cmdsubst(t.V))
+			if len(f) == 0 {
+				continue
+			}
+			vanish = false
+			s += f[0]
+			for _, field := range f[1:] {
+				str = append(str, s)
+				s = field
+			}
+		case TokenGlob:
+			vanish = false
+			match, err := filepath.Glob(t.V)
+			if match == nil || err != nil {
+				s += t.V
+				continue
+			}
+			s += match[0]
+			for _, m := range match[1:] {
+				str = append(str, s)
+				s = m
+			}
+		default:
+			panic(fmt.Errorf("Unknown Token %v", t))
+		}
+	}
+	if !vanish || len(str) > 0 {
+		str = append(str, s)
+	}
+	return
+}
+
 // Expand converts a word into a slice of strings doing glob expansion
 func (w *Word) Expand() (str []string) {
 	s := ""
 	for _, t := range w.Tokens {
 		switch t.T {
-		case TokenLiteral, TokenEnvget, TokenEnvset:
+		case TokenLiteral, TokenEnvget, TokenQuotedEnvget, TokenEnvset:
 			s += t.V
 
+		case TokenCmdsubst, TokenQuotedCmdsubst:
+			s += "$(" + t.V + ")"
+
 		case TokenGlob:
 			match, err := filepath.Glob(t.V)
 			if match == nil || err != nil {
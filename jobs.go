@@ -0,0 +1,458 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+// +build linux
+
+package goes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// A Job is a list run in the background with '&' or a foreground pipeline
+// stopped with ^Z. Each job has its own process group.
+type Job struct {
+	Id   int
+	Pgid int
+	Text string
+
+	procs   []*child
+	stopped bool
+}
+
+// StoppedError unwinds the command list of a foreground job suspended by
+// ^Z.
+type StoppedError struct {
+	Job *Job
+}
+
+func (e *StoppedError) Error() string { return e.Job.String() }
+
+// child is a forked command and its exit status, available once done is
+// closed.
+type child struct {
+	x    *exec.Cmd
+	done chan struct{}
+	err  error
+}
+
+type jobs struct {
+	sync.Mutex
+	list []*Job
+	// pgid and procs of the foreground pipeline
+	pgid  int
+	procs []*child
+	// pgrp of the interactive cli
+	pgrp int
+	// pid of the last background job
+	last int
+	// number of running in-process pipeline stages
+	stages int
+}
+
+const (
+	_P_PGID      = 2
+	_CLD_STOPPED = 5
+)
+
+// recorder saves the lines read from a Catline by blocks.
+type recorder struct {
+	io.ReadWriter
+	lines []string
+}
+
+func (r *recorder) Read(p []byte) (n int, err error) {
+	n, err = r.ReadWriter.Read(p)
+	if n > 0 {
+		r.lines = append(r.lines, string(p[:n]))
+	}
+	return
+}
+
+// watch waits for the child and records its exit status. If the child's
+// stdout is a pipe, both it and stdin are closed after exit so that the
+// adjacent stages see EOF.
+func (g *Goes) watch(x *exec.Cmd, isPipe bool) *child {
+	c := &child{x: x, done: make(chan struct{})}
+	stdin, stdout, stderr := g.std()
+	WG.Add(1)
+	go func() {
+		defer WG.Done()
+		c.err = x.Wait()
+		close(c.done)
+		if !isPipe {
+			return
+		}
+		if c.err != nil && c.err.Error() != "exit status 1" &&
+			!isBrokenPipe(c.err) {
+			fmt.Fprintln(stderr, c.err)
+		}
+		if x.Stdout != stdout {
+			m, found := x.Stdout.(io.Closer)
+			if found {
+				m.Close()
+			}
+		}
+		if x.Stdin != stdin {
+			m, found := x.Stdin.(io.Closer)
+			if found {
+				m.Close()
+			}
+		}
+	}()
+	return c
+}
+
+// Jobs returns the current job table.
+func (g *Goes) Jobs() []*Job {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	return append([]*Job{}, g.jobs.list...)
+}
+
+// ReapJobs removes and returns completed jobs.
+func (g *Goes) ReapJobs() (done []*Job) {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	list := g.jobs.list[:0]
+	for _, j := range g.jobs.list {
+		if j.Done() {
+			done = append(done, j)
+		} else {
+			list = append(list, j)
+		}
+	}
+	g.jobs.list = list
+	return
+}
+
+// FindJob returns the job given by a specification of "%N", "%%", "%+",
+// "%-", or "%TEXT" for the last job beginning with TEXT. An empty spec is
+// the current, i.e. last, job.
+func (g *Goes) FindJob(spec string) (*Job, error) {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	n := len(g.jobs.list)
+	if n == 0 {
+		return nil, errors.New("no current job")
+	}
+	switch spec {
+	case "", "%", "%%", "%+":
+		return g.jobs.list[n-1], nil
+	case "%-":
+		if n < 2 {
+			return nil, errors.New("no previous job")
+		}
+		return g.jobs.list[n-2], nil
+	}
+	if !strings.HasPrefix(spec, "%") {
+		return nil, fmt.Errorf("%s: not a job", spec)
+	}
+	if id, err := strconv.Atoi(spec[1:]); err == nil {
+		for _, j := range g.jobs.list {
+			if j.Id == id {
+				return j, nil
+			}
+		}
+	} else {
+		for i := n - 1; i >= 0; i-- {
+			if strings.HasPrefix(g.jobs.list[i].Text, spec[1:]) {
+				return g.jobs.list[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+func (g *Goes) addJob(j *Job) {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	if j.Id != 0 {
+		return
+	}
+	j.Id = 1
+	if n := len(g.jobs.list); n > 0 {
+		j.Id = g.jobs.list[n-1].Id + 1
+	}
+	g.jobs.list = append(g.jobs.list, j)
+}
+
+func (g *Goes) removeJob(j *Job) {
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	for i, t := range g.jobs.list {
+		if t == j {
+			copy(g.jobs.list[i:], g.jobs.list[i+1:])
+			g.jobs.list = g.jobs.list[:len(g.jobs.list)-1]
+			break
+		}
+	}
+}
+
+// background runs the list source text in a goes subshell as a new job.
+// The subshell has a copy of the context variables and functions.
+func (g *Goes) background(text string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if _, found := g.ByName["cli"]; !found {
+		return errors.New("&: has no cli")
+	}
+	args := []string{"cli"}
+	if g.ErrExit {
+		args = append(args, "-e")
+	}
+	if !g.ErrAbort {
+		args = append(args, "-f")
+	}
+	x := g.Fork(append(args, "-c", text)...)
+	x.Env = os.Environ()
+	for k, v := range g.EnvMap {
+		x.Env = append(x.Env, k+"="+v)
+	}
+	if s, found := g.functionsEnv(); found {
+		x.Env = append(x.Env, FunctionsEnv+"="+s)
+	}
+	x.Env = auditEnv(roleEnv(x.Env))
+	// a background job reading a job control tty is stopped with SIGTTIN
+	x.Stdin = stdin
+	if !g.JobControl {
+		f, err := os.Open(os.DevNull)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		x.Stdin = f
+	}
+	x.Stdout = stdout
+	x.Stderr = stderr
+	x.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	start := time.Now()
+	if err := x.Start(); err != nil {
+		return fmt.Errorf("child: %v: %v", x.Args, err)
+	}
+	c := g.watch(x, false)
+	if IsAudited() {
+		// the job is recorded here as well as its commands by the
+		// child cli
+		go func() {
+			<-c.done
+			Audit([]string{text, "&"}, start, c.err)
+		}()
+	}
+	j := &Job{
+		Pgid:  x.Process.Pid,
+		Text:  text,
+		procs: []*child{c},
+	}
+	g.addJob(j)
+	g.jobs.last = j.Pgid
+	if g.JobControl {
+		fmt.Fprintf(stderr, "[%d] %d\n", j.Id, j.Pgid)
+	}
+	g.Status = nil
+	return nil
+}
+
+// Foreground continues a job with the terminal and waits for it to finish
+// or stop again.
+func (g *Goes) Foreground(j *Job) error {
+	if !g.JobControl {
+		return errors.New("no job control")
+	}
+	g.setTerminalPgrp(j.Pgid)
+	j.stopped = false
+	if err := syscall.Kill(-j.Pgid, syscall.SIGCONT); err != nil {
+		return err
+	}
+	err := g.waitForeground(j)
+	if !j.stopped {
+		g.removeJob(j)
+	}
+	return err
+}
+
+// Resume continues a stopped job in the background.
+func (g *Goes) Resume(j *Job) error {
+	j.stopped = false
+	return syscall.Kill(-j.Pgid, syscall.SIGCONT)
+}
+
+// Wait for the job to finish and return its status.
+func (g *Goes) WaitJob(j *Job) error {
+	last := j.procs[len(j.procs)-1]
+	select {
+	case <-last.done:
+	case <-IntSig:
+		return ErrInterrupted
+	}
+	g.removeJob(j)
+	return last.err
+}
+
+// Signal every process of the job. A stopped job is also continued to
+// receive a SIGTERM or SIGHUP.
+func (g *Goes) SignalJob(j *Job, sig syscall.Signal) error {
+	if err := syscall.Kill(-j.Pgid, sig); err != nil {
+		return err
+	}
+	if j.stopped && (sig == syscall.SIGTERM || sig == syscall.SIGHUP) {
+		j.stopped = false
+		return syscall.Kill(-j.Pgid, syscall.SIGCONT)
+	}
+	return nil
+}
+
+// waitForeground waits for the last process of a job holding the terminal
+// to exit or for any of its processes to stop. A stopped job is added to the
+// table and returned as a StoppedError.
+func (g *Goes) waitForeground(j *Job) error {
+	defer g.setTerminalPgrp(g.jobs.pgrp)
+	sigchld := make(chan os.Signal, 1)
+	signal.Notify(sigchld, syscall.SIGCHLD)
+	defer signal.Stop(sigchld)
+	last := j.procs[len(j.procs)-1]
+	for {
+		select {
+		case <-last.done:
+			interrupted(last.err)
+			return last.err
+		default:
+		}
+		if isStopped(j.Pgid) {
+			j.stopped = true
+			g.addJob(j)
+			return &StoppedError{j}
+		}
+		select {
+		case <-last.done:
+		case <-sigchld:
+		}
+	}
+}
+
+// isBrokenPipe reports whether a child was killed by SIGPIPE or its
+// output couldn't be relayed to a closed pipe.
+func isBrokenPipe(err error) bool {
+	if ws, ok := waitStatus(err); ok &&
+		ws.Signaled() && ws.Signal() == syscall.SIGPIPE {
+		return true
+	}
+	return errors.Is(err, io.ErrClosedPipe)
+}
+
+// interrupted relays the ^C of a foreground child to the cli that, with
+// job control, isn't in the foreground process group to receive it.
+func interrupted(err error) {
+	if ws, ok := waitStatus(err); ok &&
+		ws.Signaled() && ws.Signal() == syscall.SIGINT {
+		select {
+		case IntSig <- os.Interrupt:
+		default:
+		}
+	}
+}
+
+// inStage reports whether this is the Goes of a pipeline stage or any
+// in-process pipeline stages are running. Their commands are waited upon
+// directly rather than as a foreground job as the terminal is held by the
+// pipeline.
+func (g *Goes) inStage() bool {
+	if g.shell != nil {
+		return true
+	}
+	g.jobs.Lock()
+	defer g.jobs.Unlock()
+	return g.jobs.stages > 0
+}
+
+// Done reports whether the last process of the job has exited.
+func (j *Job) Done() bool {
+	select {
+	case <-j.procs[len(j.procs)-1].done:
+		return true
+	default:
+	}
+	return false
+}
+
+// Status returns the job's exit status once Done.
+func (j *Job) Status() error {
+	return j.procs[len(j.procs)-1].err
+}
+
+// State is one of Running, Stopped, Done or Exit N.
+func (j *Job) State() string {
+	switch {
+	case j.Done():
+		if err := j.Status(); err != nil {
+			return fmt.Sprint("Exit ", ExitStatus(err))
+		}
+		return "Done"
+	case j.stopped:
+		return "Stopped"
+	}
+	return "Running"
+}
+
+func (j *Job) String() string {
+	return fmt.Sprintf("[%d]  %-8s %s", j.Id, j.State(), j.Text)
+}
+
+func waitStatus(err error) (syscall.WaitStatus, bool) {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		ws, ok := ee.Sys().(syscall.WaitStatus)
+		return ws, ok
+	}
+	return 0, false
+}
+
+// isStopped consumes and reports the stop of any process in the group.
+func isStopped(pgid int) bool {
+	var info struct {
+		signo, errno, code int32
+		_                  [29]int32
+	}
+	_, _, e := syscall.Syscall6(syscall.SYS_WAITID, _P_PGID,
+		uintptr(pgid), uintptr(unsafe.Pointer(&info)),
+		syscall.WSTOPPED|syscall.WNOHANG, 0, 0)
+	return e == 0 && info.signo == int32(syscall.SIGCHLD) &&
+		info.code == _CLD_STOPPED
+}
+
+// setTerminalPgrp gives the controlling terminal to the process group.
+// SIGTTOU is ignored while doing so from the background; it's then reset so
+// that it isn't also ignored by children.
+func (g *Goes) setTerminalPgrp(pgrp int) {
+	if !g.JobControl || pgrp == 0 {
+		return
+	}
+	pid := int32(pgrp)
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	syscall.Syscall(syscall.SYS_IOCTL, uintptr(syscall.Stdin),
+		syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&pid)))
+}
+
+// EnableJobControl puts foreground pipelines in their own process group
+// with the terminal so that ^Z stops them rather than the cli.
+func (g *Goes) EnableJobControl() {
+	var pgrp int32
+	syscall.Syscall(syscall.SYS_IOCTL, uintptr(syscall.Stdin),
+		syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgrp)))
+	if pgrp == 0 || int(pgrp) != syscall.Getpgrp() {
+		return
+	}
+	g.jobs.pgrp = int(pgrp)
+	g.JobControl = true
+}
@@ -0,0 +1,224 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package lang
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CatalogDir has a directory of message catalogs for each language, e.g.
+// /usr/share/goes/locale/fr_FR. The catalogs of the preferred language are
+// those of its directory named LANG, LANG without the encoding, or just the
+// language, e.g. fr_FR.UTF-8, fr_FR, or fr.
+var CatalogDir = "/usr/share/goes/locale"
+
+// A Catalog has the text of each command path, e.g. "ip route", by field,
+// i.e. "apropos", "man", or "usage".
+//
+// The JSON encoding of a catalog is an object of such objects. A gettext
+// catalog has the path and field of each entry in its context like this,
+//
+//	msgctxt "ip route|apropos"
+//	msgid "routing table management"
+//	msgstr "gestion de la table de routage"
+type Catalog map[string]map[string]string
+
+var catalogs struct {
+	sync.Mutex
+	byLang map[string]Catalog
+}
+
+// Preferred returns the language of the text of Alt.String.
+func Preferred() string {
+	if len(Lang) == 0 {
+		Lang = os.Getenv("LANG")
+	}
+	if len(Lang) > 0 {
+		return Lang
+	}
+	return Default
+}
+
+// Merge returns the alternatives with the catalog text, if any, of the
+// command path and field in the preferred language.
+func (m Alt) Merge(path, field string) Alt {
+	lang := Preferred()
+	s, found := Lookup(lang, path, field)
+	if !found {
+		return m
+	}
+	merged := make(Alt, len(m)+1)
+	for k, v := range m {
+		merged[k] = v
+	}
+	merged[lang] = s
+	return merged
+}
+
+// MergeString returns the catalog text, if any, of the command path and
+// field in the preferred language instead of s.
+func MergeString(s, path, field string) string {
+	if t, found := Lookup(Preferred(), path, field); found {
+		return t
+	}
+	return s
+}
+
+// Lookup returns the catalog text of the command path and field in the
+// given language.
+func Lookup(lang, path, field string) (string, bool) {
+	catalogs.Lock()
+	defer catalogs.Unlock()
+	if catalogs.byLang == nil {
+		catalogs.byLang = make(map[string]Catalog)
+	}
+	catalog, found := catalogs.byLang[lang]
+	if !found {
+		catalog = make(Catalog)
+		for _, dir := range langDirs(lang) {
+			// catalogs that can't be read are ignored
+			LoadCatalog(catalog, filepath.Join(CatalogDir, dir))
+		}
+		catalogs.byLang[lang] = catalog
+	}
+	s, found := catalog[path][field]
+	return s, found && len(s) > 0
+}
+
+// langDirs returns the directories of the language from least to most
+// specific so that the entries of the latter take precedence.
+func langDirs(lang string) []string {
+	dirs := []string{lang}
+	if i := strings.IndexAny(lang, ".@"); i > 0 {
+		lang = lang[:i]
+		dirs = append([]string{lang}, dirs...)
+	}
+	if i := strings.Index(lang, "_"); i > 0 {
+		dirs = append([]string{lang[:i]}, dirs...)
+	}
+	return dirs
+}
+
+// LoadCatalog adds the entries of each *.json and *.po file of the
+// directory to the catalog.
+func LoadCatalog(catalog Catalog, dir string) error {
+	fns, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	pos, err := filepath.Glob(filepath.Join(dir, "*.po"))
+	if err != nil {
+		return err
+	}
+	for _, fn := range append(fns, pos...) {
+		f, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(fn, ".json") {
+			err = catalog.readJSON(f)
+		} else {
+			err = catalog.readPO(f)
+		}
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", fn, err)
+		}
+	}
+	return nil
+}
+
+func (catalog Catalog) add(path, field, s string) {
+	if catalog[path] == nil {
+		catalog[path] = make(map[string]string)
+	}
+	catalog[path][field] = s
+}
+
+func (catalog Catalog) readJSON(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var entries Catalog
+	if err = json.Unmarshal(b, &entries); err != nil {
+		return err
+	}
+	for path, fields := range entries {
+		for field, s := range fields {
+			catalog.add(path, field, s)
+		}
+	}
+	return nil
+}
+
+// readPO adds the translated entries of a gettext catalog; those without
+// a "path|field" context or msgstr are ignored.
+func (catalog Catalog) readPO(r io.Reader) error {
+	var (
+		ctxt, msgid, str string
+		last             *string
+		line             int
+	)
+	flush := func() {
+		if i := strings.LastIndex(ctxt, "|"); i >= 0 && len(str) > 0 {
+			catalog.add(ctxt[:i], ctxt[i+1:], str)
+		}
+		ctxt, msgid, str, last = "", "", "", nil
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		keyword := s
+		if i := strings.IndexByte(s, ' '); i > 0 {
+			keyword = s[:i]
+		}
+		switch keyword {
+		case "", "#":
+			continue
+		case "msgctxt":
+			flush()
+			last = &ctxt
+		case "msgid":
+			// an entry without context follows that with msgstr
+			if last == &str {
+				flush()
+			}
+			last = &msgid
+		case "msgstr":
+			last = &str
+		default:
+			if strings.HasPrefix(s, "#") {
+				continue
+			}
+			if !strings.HasPrefix(s, `"`) || last == nil {
+				return fmt.Errorf("%d: %q unexpected", line, s)
+			}
+			keyword = ""
+		}
+		quoted := strings.TrimSpace(strings.TrimPrefix(s, keyword))
+		t, err := strconv.Unquote(quoted)
+		if err != nil {
+			return fmt.Errorf("%d: %v", line, err)
+		}
+		*last += t
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
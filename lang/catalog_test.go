@@ -0,0 +1,57 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package lang
+
+import (
+	"strings"
+	"testing"
+)
+
+const po = `# comment
+msgid ""
+msgstr "Content-Type: text/plain; charset=UTF-8\n"
+
+msgctxt "ip route|apropos"
+msgid "routing table management"
+msgstr "gestion de la table de routage"
+
+msgctxt "ip route|man"
+msgid ""
+"\n"
+"DESCRIPTION\n"
+msgstr ""
+"\n"
+"DESCRIPTION\n"
+"\tGérer les routes.\n"
+
+msgctxt "ip route|usage"
+msgid "route"
+msgstr ""
+`
+
+func TestCatalog(t *testing.T) {
+	catalog := make(Catalog)
+	if err := catalog.readPO(strings.NewReader(po)); err != nil {
+		t.Fatal(err)
+	}
+	err := catalog.readJSON(strings.NewReader(`{
+		"ip route": {"usage": "route [ ARGUMENTS ]"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for field, expect := range map[string]string{
+		"apropos": "gestion de la table de routage",
+		"man":     "\nDESCRIPTION\n\tGérer les routes.\n",
+		"usage":   "route [ ARGUMENTS ]",
+	} {
+		if s := catalog["ip route"][field]; s != expect {
+			t.Fatalf("%s: %q != %q", field, s, expect)
+		}
+	}
+	if _, found := catalog[""]; found {
+		t.Fatal("entry without context")
+	}
+}
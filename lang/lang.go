@@ -14,6 +14,9 @@
 // Or re-initialize the default with a build tag as shown in french.go
 //
 // go test -tags french -v
+//
+// The text of commands may also be translated without a rebuild by message
+// catalogs as described in catalog.go.
 package lang
 
 import "os"
@@ -0,0 +1,214 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// A Diagnostic is a problem found by Lint at a line of a script.
+type Diagnostic struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d: %v", d.File, d.Line, d.Err)
+}
+
+// Lint parses the named script without running it and returns its
+// syntax errors, unbalanced blocks, and commands that aren't in ByName,
+// the builtins, or the functions and aliases of the context or script.
+func (g *Goes) Lint(name string, r io.Reader) ([]Diagnostic, error) {
+	src := &lintLines{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		src.lines = append(src.lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	l := &linter{
+		name:    name,
+		src:     src,
+		defined: make(map[string]bool),
+	}
+	// functions and aliases may be used before they're defined
+	for _, line := range src.lines {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "function":
+				l.defined[fields[i+1]] = true
+			case "alias":
+				alias := fields[i+1]
+				if eq := strings.Index(alias, "="); eq > 0 {
+					l.defined[alias[:eq]] = true
+				}
+			}
+		}
+	}
+	catline := g.Catline
+	g.Catline = src
+	g.linter = l
+	defer func() {
+		g.Catline = catline
+		g.linter = nil
+	}()
+	l.script(g)
+	return l.diags, nil
+}
+
+type linter struct {
+	name    string
+	src     *lintLines
+	defined map[string]bool
+	diags   []Diagnostic
+}
+
+// script checks each command list read from Catline until EOF.
+func (l *linter) script(g *Goes) {
+	for {
+		// that of a subshell was read with the line that ends it
+		start := l.src.n
+		if g.Catline == l.src {
+			start++
+		}
+		ls, err := shellutils.Parse("", g.Catline)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			l.report(err)
+			continue
+		}
+		for len(ls.Cmds) != 0 {
+			ls, _, _, err = g.ProcessList(*ls)
+			if err == io.EOF {
+				l.diags = append(l.diags, Diagnostic{
+					File: l.name,
+					Line: start,
+					Err:  errors.New("unexpected end of file in block"),
+				})
+				break
+			}
+			if err != nil {
+				l.report(err)
+				break
+			}
+		}
+	}
+}
+
+// subshell checks the source text of a subshell, which is otherwise run
+// by a cli. Its problems are reported at the line that ends it.
+func (l *linter) subshell(g *Goes, text string) {
+	script := lines(strings.Split(text, "\n"))
+	catline := g.Catline
+	g.Catline = &script
+	l.script(g)
+	g.Catline = catline
+}
+
+// report adds a diagnostic at the last line read.
+func (l *linter) report(err error) {
+	l.diags = append(l.diags, Diagnostic{
+		File: l.name,
+		Line: l.src.n,
+		Err:  err,
+	})
+}
+
+// command checks the name of a simple command and skips the text of any
+// here document that follows.
+func (l *linter) command(g *Goes, cl shellutils.Cmdline) {
+	for i, w := range cl.Cmds {
+		s := w.String()
+		if (s == "<<" || s == "<<-") && i+1 < len(cl.Cmds) {
+			l.src.heredoc = cl.Cmds[i+1].String()
+		}
+	}
+	var name string
+	for _, w := range cl.Cmds {
+		if isAssignment(w) {
+			continue
+		}
+		for _, t := range w.Tokens {
+			if t.T != shellutils.TokenLiteral {
+				return
+			}
+		}
+		name = w.String()
+		break
+	}
+	switch name {
+	case "":
+	case "then", "else", "elif", "fi", "do", "done", "esac", "}", ")":
+		l.report(fmt.Errorf("unexpected %s", name))
+	default:
+		if _, found := g.lookup(name); found {
+			return
+		}
+		if _, found := g.Builtins()[name]; found {
+			return
+		}
+		if _, found := g.FunctionMap[name]; found {
+			return
+		}
+		if _, found := g.AliasMap[name]; found {
+			return
+		}
+		if !l.defined[name] {
+			l.report(fmt.Errorf("%s: command not found", name))
+		}
+	}
+}
+
+func isAssignment(w shellutils.Word) bool {
+	for _, t := range w.Tokens {
+		if t.T == shellutils.TokenEnvset {
+			return true
+		}
+	}
+	return false
+}
+
+// lintLines is the Catline of a script being linted. It counts the lines
+// read and skips those of a here document until its label.
+type lintLines struct {
+	lines   []string
+	n       int
+	heredoc string
+}
+
+func (l *lintLines) Read(p []byte) (int, error) {
+	for len(l.heredoc) > 0 && l.n < len(l.lines) {
+		if l.lines[l.n] == l.heredoc {
+			l.heredoc = ""
+		}
+		l.n++
+	}
+	if l.n >= len(l.lines) {
+		return 0, io.EOF
+	}
+	s := l.lines[l.n]
+	l.n++
+	n := copy(p, s)
+	if len(s) > len(p) {
+		return n, errors.New("input too long")
+	}
+	return n, nil
+}
+
+func (l *lintLines) Write(p []byte) (int, error) {
+	return len(p), nil
+}
@@ -35,10 +35,29 @@ func (g *Goes) Man() lang.Alt {
 OPTIONS
 	-d	debug block handling
 	-x	print command trace
+	-e	terminate script on any failed command
 	-f	don't terminate script on error
 	-	execute standard input script
 	SCRIPT	execute named script file
 
+PLUGINS
+	A command that isn't built in may be an executable of
+	/usr/lib/goes/plugins or one named goes-COMMAND in $PATH. Goes runs
+	"PLUGIN --goes-describe" for its help as this JSON object,
+
+	{"usage": "...", "apropos": "...", "man": "...", "complete": true}
+
+	With "complete", it also runs "PLUGIN --goes-complete [ARG]..." for
+	its completions, one per line.
+
+	The description of each plugin is kept in /run/goes/plugins until
+	it changes. Executables of goes machines, like /usr/bin/goes-MACHINE,
+	aren't plugins; nor are other executables in $PATH, run those with
+	"!".
+
+	The plugins are found as goes starts. Run "rehash" for a running
+	cli to find those installed since.
+
 SEE ALSO
 	goes apropos [COMMAND], goes man COMMAND`,
 		}
@@ -48,9 +67,12 @@ SEE ALSO
 }
 
 func (g *Goes) man(args ...string) error {
-	var cmds []cmd.Cmd
+	var (
+		cmds  []cmd.Cmd
+		names []string
+	)
 	for i, arg := range args {
-		v := g.ByName[arg]
+		v, _ := g.lookup(arg)
 		if v == nil {
 			if i == 0 {
 				return fmt.Errorf("%s: not found", arg)
@@ -58,19 +80,22 @@ func (g *Goes) man(args ...string) error {
 			break
 		}
 		cmds = append(cmds, v)
+		names = append(names, arg)
 	}
 	if len(cmds) == 0 {
 		cmds = []cmd.Cmd{g}
+		names = []string{""}
 	}
 	for i, v := range cmds {
 		if i > 0 {
 			fmt.Println()
 		}
 		fmt.Print(section.name, "\n\t", v, " - ",
-			v.Apropos(), "\n\n", section.synopsis, "\n\t",
-			strings.TrimSpace(v.Usage()), "\n")
+			g.aproposOf(names[i], v), "\n\n",
+			section.synopsis, "\n\t",
+			strings.TrimSpace(g.usageOf(names[i], v)), "\n")
 		if method, found := v.(maner); found {
-			man := method.Man().String()
+			man := g.manOf(names[i], method).String()
 			if !strings.HasPrefix(man, "\n") {
 				fmt.Println()
 			}
@@ -0,0 +1,284 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/lang"
+)
+
+// PluginDir has executables run as commands of the top goes that aren't
+// in its ByName or builtins. So are those named PluginPrefix+NAME in the
+// directories of $PATH.
+var (
+	PluginDir    = "/usr/lib/goes/plugins"
+	PluginPrefix = "goes-"
+)
+
+// PluginTimeout limits each run of a plugin to describe or complete itself.
+var PluginTimeout = 2 * time.Second
+
+// PluginCache has the description of each plugin, so that it's run to
+// describe itself again only once changed. It's not written if empty.
+var PluginCache = "/run/goes/plugins"
+
+// goesModule is that of the goes machines, which aren't plugins.
+const goesModule = "github.com/platinasystems/goes"
+
+// PluginDescription is the JSON encoding of a plugin's answer to
+// "--goes-describe". With "complete" true, the plugin also answers
+// "--goes-complete [ARG]..." with a completion per line.
+type PluginDescription struct {
+	Usage    string `json:"usage"`
+	Apropos  string `json:"apropos"`
+	Man      string `json:"man,omitempty"`
+	Complete bool   `json:"complete,omitempty"`
+}
+
+type plugin struct {
+	name, path string
+
+	once sync.Once
+	desc PluginDescription
+}
+
+// lookup returns the named command, which may be a plugin of the top goes.
+func (g *Goes) lookup(name string) (cmd.Cmd, bool) {
+	if g.shell != nil {
+		return g.shell.lookup(name)
+	}
+	if v, found := g.ByName[name]; found || g.parent != nil {
+		return v, found
+	}
+	if _, found := g.Builtins()[name]; found || len(name) == 0 {
+		return nil, false
+	}
+	g.cache.Lock()
+	defer g.cache.Unlock()
+	if p, found := g.cache.plugins[name]; found {
+		return p, true
+	}
+	return nil, false
+}
+
+// loadPlugins finds the plugins of the top goes once, as it starts, rather
+// than with a lookup; rehash finds them again.
+func (g *Goes) loadPlugins() {
+	if g.parent != nil {
+		return
+	}
+	g.cache.Lock()
+	defer g.cache.Unlock()
+	if g.cache.plugins != nil {
+		return
+	}
+	g.cache.plugins = make(map[string]*plugin)
+	add := func(name, path string) {
+		if _, found := g.ByName[name]; found {
+			return
+		}
+		if _, found := g.cache.plugins[name]; found {
+			return
+		}
+		if fi, err := os.Stat(path); err != nil || fi.IsDir() ||
+			fi.Mode()&0111 == 0 || isMachine(path) {
+			return
+		}
+		g.cache.plugins[name] = &plugin{name: name, path: path}
+	}
+	for _, name := range dirnames(PluginDir) {
+		add(name, filepath.Join(PluginDir, name))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		for _, fn := range dirnames(dir) {
+			name := strings.TrimPrefix(fn, PluginPrefix)
+			if len(name) > 0 && name != fn {
+				add(name, filepath.Join(dir, fn))
+			}
+		}
+	}
+}
+
+// rehash finds the plugins of the top goes again, e.g. once one has been
+// installed.
+func (g *Goes) rehash(args ...string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%v: unexpected", args)
+	}
+	for g.shell != nil || g.parent != nil {
+		if g.shell != nil {
+			g = g.shell
+		} else {
+			g = g.parent
+		}
+	}
+	g.cache.Lock()
+	g.cache.plugins = nil
+	g.cache.names = nil
+	g.cache.Unlock()
+	g.loadPlugins()
+	return nil
+}
+
+// isMachine reports whether the executable is built with this module, i.e.
+// is a goes machine like /usr/bin/goes-MACHINE rather than a plugin.
+func isMachine(path string) bool {
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if bi.Main.Path == goesModule {
+		return true
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == goesModule {
+			return true
+		}
+	}
+	return false
+}
+
+// dirnames returns the names in the directory without the stat of each,
+// as most in $PATH aren't plugins.
+func dirnames(dir string) []string {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	names, _ := f.Readdirnames(-1)
+	return names
+}
+
+func (p *plugin) String() string { return p.name }
+
+func (p *plugin) Usage() string {
+	if usage := p.describe().Usage; len(usage) > 0 {
+		return usage
+	}
+	return p.name + " [ARG]..."
+}
+
+func (p *plugin) Apropos() lang.Alt {
+	apropos := p.describe().Apropos
+	if len(apropos) == 0 {
+		apropos = "external command"
+	}
+	return lang.Alt{lang.EnUS: apropos}
+}
+
+func (p *plugin) Man() lang.Alt {
+	man := p.describe().Man
+	if len(man) == 0 {
+		man = fmt.Sprint("\nDESCRIPTION\n\tThe plugin, ", p.path, ".")
+	}
+	return lang.Alt{lang.EnUS: man}
+}
+
+func (p *plugin) Complete(args ...string) []string {
+	if !p.describe().Complete {
+		if len(args) == 0 {
+			return nil
+		}
+		completions, _ := filepath.Glob(args[len(args)-1] + "*")
+		return completions
+	}
+	out, err := p.output(append([]string{"--goes-complete"}, args...)...)
+	if err != nil {
+		return nil
+	}
+	var completions []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if s := scanner.Text(); len(s) > 0 {
+			completions = append(completions, s)
+		}
+	}
+	return completions
+}
+
+// Main replaces this forked goes with the plugin.
+func (p *plugin) Main(args ...string) error {
+	return syscall.Exec(p.path, append([]string{p.path}, args...),
+		os.Environ())
+}
+
+// describe returns the plugin's answer to "--goes-describe", which is kept
+// in PluginCache until the plugin changes.
+func (p *plugin) describe() PluginDescription {
+	p.once.Do(func() {
+		fi, err := os.Stat(p.path)
+		if err != nil {
+			return
+		}
+		cached := pluginCached{
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+		}
+		fn := p.cacheFile()
+		if b, err := ioutil.ReadFile(fn); err == nil {
+			var c pluginCached
+			if json.Unmarshal(b, &c) == nil && c.Size == cached.Size &&
+				c.ModTime.Equal(cached.ModTime) {
+				p.desc = c.Description
+				return
+			}
+		}
+		out, err := p.output("--goes-describe")
+		if err != nil {
+			return
+		}
+		json.Unmarshal(out, &p.desc)
+		if len(fn) == 0 {
+			return
+		}
+		cached.Description = p.desc
+		if b, err := json.Marshal(cached); err == nil &&
+			os.MkdirAll(PluginCache, 0755) == nil {
+			ioutil.WriteFile(fn, b, 0644)
+		}
+	})
+	return p.desc
+}
+
+// pluginCached is the description of a plugin kept with the size and
+// modification time of its executable.
+type pluginCached struct {
+	Size        int64             `json:"size"`
+	ModTime     time.Time         `json:"mod_time"`
+	Description PluginDescription `json:"description"`
+}
+
+// cacheFile is that of the plugin's description in PluginCache, named by
+// its path, e.g. /run/goes/plugins/usr%bin%goes-foo.json
+func (p *plugin) cacheFile() string {
+	if len(PluginCache) == 0 {
+		return ""
+	}
+	name := strings.ReplaceAll(strings.TrimPrefix(p.path, "/"), "/", "%")
+	return filepath.Join(PluginCache, name+".json")
+}
+
+func (p *plugin) output(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		PluginTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, p.path, args...).Output()
+}
@@ -0,0 +1,296 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/platinasystems/goes/external/parms"
+	"github.com/platinasystems/goes/internal/shellutils"
+	"github.com/platinasystems/url"
+)
+
+// redirect removes the redirections from the command arguments and returns
+// the resulting stdin, stdout and stderr. Opened files are added to closers.
+//
+// The numbered redirections, e.g. 2>&1, are applied last and in order, so
+// a duplicate is of the command's stdout or stderr after any preceding
+// redirection.
+func (g *Goes) redirect(args []string, stdin io.Reader, stdout, stderr io.Writer, closers *[]io.Closer) ([]string, io.Reader, io.Writer, io.Writer, error) {
+	redirs, args, err := shellutils.Redirections(args)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	in := stdin
+	if !g.isStdinRedirected(stdin) {
+		var iparm *parms.Parms
+		iparm, args = parms.New(args, "<", "<<", "<<-")
+		if fn := iparm.ByName["<"]; len(fn) > 0 {
+			rc, err := url.Open(fn)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			in = rc
+			*closers = append(*closers, rc)
+		} else if len(iparm.ByName["<<"]) > 0 ||
+			len(iparm.ByName["<<-"]) > 0 {
+			var trim bool
+			lbl := iparm.ByName["<<"]
+			if len(lbl) == 0 {
+				lbl = iparm.ByName["<<-"]
+				trim = true
+			}
+			r, w, err := os.Pipe()
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			in = r
+			*closers = append(*closers, r)
+			WG.Add(1)
+			go func(w io.WriteCloser, lbl string) {
+				defer WG.Done()
+				defer w.Close()
+				prompt := "<<" + fn + " "
+				for {
+					g.Catline.Write([]byte(prompt))
+					buf := make([]byte, 1024)
+					n, err := g.Catline.Read(buf)
+					s := string(buf[0:n])
+					if err != nil || s == lbl {
+						break
+					}
+					if trim {
+						s = strings.TrimLeft(s, " \t")
+					}
+					fmt.Fprintln(w, s)
+				}
+			}(w, lbl)
+		}
+	}
+	out := stdout
+	if !g.isStdoutRedirected(stdout) {
+		var oparm *parms.Parms
+		oparm, args = parms.New(args, ">", ">>", ">>>", ">>>>")
+		if fn := oparm.ByName[">"]; len(fn) > 0 {
+			wc, err := createURL(fn)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			out = wc
+			*closers = append(*closers, wc)
+		} else if fn = oparm.ByName[">>"]; len(fn) > 0 {
+			wc, err := appendURL(fn)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			out = wc
+			*closers = append(*closers, wc)
+		} else if fn := oparm.ByName[">>>"]; len(fn) > 0 {
+			wc, err := createURL(fn)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			out = io.MultiWriter(stdout, wc)
+			*closers = append(*closers, wc)
+		} else if fn := oparm.ByName[">>"]; len(fn) > 0 {
+			wc, err := appendURL(fn)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			out = io.MultiWriter(stdout, wc)
+			*closers = append(*closers, wc)
+		}
+	}
+	errw := stderr
+	for _, r := range redirs {
+		var w io.Writer
+		switch r.Dup {
+		case 1:
+			w = out
+		case 2:
+			w = errw
+		default:
+			create := createURL
+			if r.Append {
+				create = appendURL
+			}
+			wc, err := create(r.URL)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			*closers = append(*closers, wc)
+			w = wc
+		}
+		if r.Fd != 2 {
+			out = w
+		}
+		if r.Fd != 1 {
+			errw = w
+		}
+	}
+	return args, in, out, errw, nil
+}
+
+// stdioMutex is held by an in-process command while it has replaced the
+// standard files of the process; see stdio.
+var stdioMutex sync.Mutex
+
+// stdio runs an in-process command with the standard files of the process
+// replaced by its redirections, if any, as such commands write to os.Stdout
+// and os.Stderr rather than those of their pipeline. Redirections that
+// aren't files are relayed through a pipe, as is input that isn't a file,
+// e.g. that of an earlier in-process stage.
+//
+// The standard files are those of the whole process, so only the shell
+// replaces them; a concurrent pipeline stage instead forks such a command,
+// see mustFork, as each stage of a bash pipeline is a subshell. The nested
+// commands of one that holds stdioMutex, e.g. source, don't take it again.
+//
+// A test can't fork itself, so there the in-process commands of concurrent
+// stages take turns with stdioMutex. So that these turns don't deadlock,
+// input that isn't a file is read in full beforehand.
+func (g *Goes) stdio(in io.Reader, out, errw io.Writer, f func() error) error {
+	var (
+		wg       sync.WaitGroup
+		restores []func()
+	)
+	if _, ok := in.(*os.File); !ok && in != nil && g.inTest {
+		b, err := ioutil.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		in = bytes.NewReader(b)
+	}
+	held := g.stdioHeld
+	if !held {
+		stdioMutex.Lock()
+		g.stdioHeld = true
+	}
+	defer func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+		if !held {
+			g.stdioHeld = false
+			stdioMutex.Unlock()
+		}
+		wg.Wait()
+	}()
+	setw := func(p **os.File, w io.Writer) error {
+		saved := *p
+		if f, ok := w.(*os.File); ok {
+			*p = f
+			restores = append(restores, func() { *p = saved })
+			return nil
+		}
+		r, pw, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		relay(&wg, w, r)
+		*p = pw
+		restores = append(restores, func() {
+			*p = saved
+			pw.Close()
+		})
+		return nil
+	}
+	if in != nil && in != io.Reader(os.Stdin) {
+		saved := os.Stdin
+		if f, ok := in.(*os.File); ok {
+			os.Stdin = f
+			restores = append(restores, func() { os.Stdin = saved })
+		} else {
+			pr, w, err := os.Pipe()
+			if err != nil {
+				return err
+			}
+			// once the command is done, the closed pipe fails the
+			// copy rather than it reading the rest of the input
+			go func() {
+				io.Copy(w, in)
+				w.Close()
+			}()
+			os.Stdin = pr
+			restores = append(restores, func() {
+				os.Stdin = saved
+				pr.Close()
+			})
+		}
+	}
+	if out != io.Writer(os.Stdout) {
+		if err := setw(&os.Stdout, out); err != nil {
+			return err
+		}
+	}
+	if errw != io.Writer(os.Stderr) {
+		if err := setw(&os.Stderr, errw); err != nil {
+			return err
+		}
+	}
+	return f()
+}
+
+// mustFork reports whether an in-process command with the given standard
+// files is instead forked, as it's of a concurrent pipeline stage that
+// would have to replace those of the process; see stdio.
+func (g *Goes) mustFork(in io.Reader, out, errw io.Writer) bool {
+	if g.shell == nil || g.inTest || g.stdioHeld {
+		return false
+	}
+	stdin, stdout, stderr := g.std()
+	return (in != nil && in != io.Reader(stdin)) ||
+		out != io.Writer(stdout) || errw != io.Writer(stderr)
+}
+
+// relay copies what's written to the pipe to w, no more than the pipe
+// holds ahead of the reader of w. Once w fails, the pipe is closed so that
+// its writer fails too.
+func relay(wg *sync.WaitGroup, w io.Writer, r *os.File) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer r.Close()
+		io.Copy(w, r)
+	}()
+}
+
+// std returns the standard files of the process, which are those of an
+// in-process command while it runs; see stdio. Those of a concurrent stage
+// are of its shell as it started the pipeline, so that the stage doesn't
+// wait upon an in-process command of the shell.
+func (g *Goes) std() (stdin, stdout, stderr *os.File) {
+	if g.stdioHeld {
+		return os.Stdin, os.Stdout, os.Stderr
+	}
+	if g.shell != nil && g.files[1] != nil {
+		return g.files[0], g.files[1], g.files[2]
+	}
+	stdioMutex.Lock()
+	defer stdioMutex.Unlock()
+	return os.Stdin, os.Stdout, os.Stderr
+}
+
+// createURL and appendURL are url.Create and url.Append if the role of the
+// process, if any, may write the URL; see RolesFile.
+func createURL(fn string) (io.WriteCloser, error) {
+	if err := authorize([]string{">", fn}); err != nil {
+		return nil, err
+	}
+	return url.Create(fn)
+}
+
+func appendURL(fn string) (io.WriteCloser, error) {
+	if err := authorize([]string{">", fn}); err != nil {
+		return nil, err
+	}
+	return url.Append(fn)
+}
@@ -0,0 +1,73 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestStdioStopsEarly runs an endless in-process stage into a command that
+// reads only a line of its input, which must end the pipeline.
+func TestStdioStopsEarly(t *testing.T) {
+	g := &Goes{NAME: "goes"}
+	var line string
+	produced := make(chan error, 1)
+	pipeline := []Stage{
+		{
+			Run: func(stdin io.Reader, stdout, stderr io.Writer) error {
+				for {
+					if _, err := io.WriteString(stdout,
+						"y\n"); err != nil {
+						produced <- err
+						return err
+					}
+				}
+			},
+			InProcess: func() bool { return true },
+			g:         g.stage(),
+		},
+		{
+			Run: func(stdin io.Reader, stdout, stderr io.Writer) error {
+				return g.stdio(stdin, stdout, stderr, func() error {
+					s, err := bufio.NewReader(os.Stdin).
+						ReadString('\n')
+					line = s
+					return err
+				})
+			},
+			InProcess: func() bool { return true },
+		},
+	}
+	var closers []io.Closer
+	run, err := g.MakePipefun(pipeline, &closers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- run(nil, ioutil.Discard, ioutil.Discard)
+	}()
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline didn't end")
+	}
+	if err != nil {
+		t.Error(err)
+	}
+	if line != "y\n" {
+		t.Errorf("read %q", line)
+	}
+	select {
+	case <-produced:
+	default:
+		t.Error("producer didn't fail")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// RolesFile has the roles of remote sessions and the commands that each
+// may run, like this,
+//
+//	# ROLE then allow or deny patterns of command words
+//	role operator
+//		allow ip * show
+//		allow ip route show
+//		allow eeprom
+//		deny *
+//	role admin
+//		allow *
+//
+//	# the roles of users, ssh keys by fingerprint, and all others
+//	user alice admin
+//	key SHA256:2dJf0nqV9... operator
+//	default operator
+//
+// A pattern matches a command with at least as many words, each of which
+// matches its pattern word as with path.Match. The first matching rule
+// applies; commands without a match are denied. An output redirection is
+// the command "> URL", e.g.
+//
+//	allow > /tmp/*
+var RolesFile = "/etc/goes/sshd/roles"
+
+// AuthRoleEnv names the role of a session. The commands of a process with
+// this in its environment, and those of its children, are limited to
+// those allowed the role.
+const AuthRoleEnv = "GOES_ROLE"
+
+// authRole is that of the process when started, so that it can't be
+// changed by the session with export or env.
+var authRole = os.Getenv(AuthRoleEnv)
+
+var authRoles struct {
+	once  sync.Once
+	roles *Roles
+	err   error
+}
+
+type Roles struct {
+	Rules   map[string][]Rule
+	ByUser  map[string]string
+	ByKey   map[string]string
+	Default string
+}
+
+type Rule struct {
+	Allow   bool
+	Pattern []string
+}
+
+// LoadRoles reads the named file.
+func LoadRoles(fn string) (*Roles, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	roles, err := ReadRoles(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", fn, err)
+	}
+	return roles, nil
+}
+
+// ReadRoles parses the format described with RolesFile.
+func ReadRoles(r io.Reader) (*Roles, error) {
+	roles := &Roles{
+		Rules:  make(map[string][]Rule),
+		ByUser: make(map[string]string),
+		ByKey:  make(map[string]string),
+	}
+	var role string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := scanner.Text()
+		if i := strings.IndexByte(s, '#'); i >= 0 {
+			s = s[:i]
+		}
+		fields := strings.Fields(s)
+		if len(fields) == 0 {
+			continue
+		}
+		switch keyword := fields[0]; {
+		case keyword == "role" && len(fields) == 2:
+			role = fields[1]
+			if _, found := roles.Rules[role]; !found {
+				roles.Rules[role] = nil
+			}
+		case keyword == "allow" || keyword == "deny":
+			if len(role) == 0 {
+				return nil, fmt.Errorf("%d: %s: outside role",
+					line, keyword)
+			}
+			if len(fields) == 1 {
+				return nil, fmt.Errorf("%d: PATTERN: missing",
+					line)
+			}
+			for _, pattern := range fields[1:] {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("%d: %s: %v",
+						line, pattern, err)
+				}
+			}
+			roles.Rules[role] = append(roles.Rules[role], Rule{
+				Allow:   keyword == "allow",
+				Pattern: fields[1:],
+			})
+		case keyword == "user" && len(fields) == 3:
+			roles.ByUser[fields[1]] = fields[2]
+		case keyword == "key" && len(fields) == 3:
+			roles.ByKey[fields[1]] = fields[2]
+		case keyword == "default" && len(fields) == 2:
+			roles.Default = fields[1]
+		default:
+			return nil, fmt.Errorf("%d: %q: invalid", line, s)
+		}
+	}
+	return roles, scanner.Err()
+}
+
+// Of returns the role of the ssh key fingerprint or, failing that, the
+// user; or the default role, if any.
+func (roles *Roles) Of(user, fingerprint string) (string, bool) {
+	if role, found := roles.ByKey[fingerprint]; found {
+		return role, true
+	}
+	if role, found := roles.ByUser[user]; found {
+		return role, true
+	}
+	return roles.Default, len(roles.Default) > 0
+}
+
+// Allows returns whether the role may run the command.
+func (roles *Roles) Allows(role string, args []string) bool {
+	for _, rule := range roles.Rules[role] {
+		if rule.Matches(args) {
+			return rule.Allow
+		}
+	}
+	return false
+}
+
+func (rule Rule) Matches(args []string) bool {
+	if len(args) < len(rule.Pattern) {
+		return false
+	}
+	for i, pattern := range rule.Pattern {
+		if matched, _ := path.Match(pattern, args[i]); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// authorize returns an error if the role of this process, if any, may not
+// run the command.
+func authorize(args []string) error {
+	if len(authRole) == 0 {
+		return nil
+	}
+	authRoles.once.Do(func() {
+		authRoles.roles, authRoles.err = LoadRoles(RolesFile)
+	})
+	if authRoles.err != nil {
+		return fmt.Errorf("%s: %v", args[0], authRoles.err)
+	}
+	if !authRoles.roles.Allows(authRole, args) {
+		return fmt.Errorf("%s: not permitted",
+			strings.Join(args, " "))
+	}
+	return nil
+}
+
+// roleEnv adds the role of this process to the environment of a child so
+// that it prevails over that of the session's variables.
+func roleEnv(env []string) []string {
+	if len(authRole) == 0 {
+		return env
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	return append(env, AuthRoleEnv+"="+authRole)
+}
@@ -0,0 +1,77 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/cmd/echo"
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// TestAuthorizeRedirected checks that a denied command can't be split by
+// its redirections.
+func TestAuthorizeRedirected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	roles, err := ReadRoles(strings.NewReader(`
+role operator
+	deny echo secret
+	allow echo
+	allow > ` + dir + `/*
+	deny *
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authRoles.once.Do(func() {})
+	authRoles.roles, authRoles.err = roles, nil
+	authRole = "operator"
+	defer func() { authRole = "" }()
+	if IntSig == nil {
+		// as made by Main
+		IntSig = make(chan os.Signal, 1)
+	}
+	out := filepath.Join(dir, "out")
+	for script, permitted := range map[string]bool{
+		"echo hello > " + out:                   true,
+		"echo < /dev/null hello > " + out:       true,
+		"echo < /dev/null secret > " + out:      false,
+		"echo << EOF secret > " + out + "\nEOF": false,
+		"echo > " + out + " secret":             false,
+		"echo hello > /etc/goes/hello":          false,
+	} {
+		text := lines(strings.Split(script, "\n"))
+		g := &Goes{
+			ByName:  map[string]cmd.Cmd{"echo": echo.Command{}},
+			Catline: &text,
+			inTest:  true,
+		}
+		ls, err := shellutils.Parse("", g.Catline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, run, err := g.ProcessList(*ls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = run(os.Stdin, os.Stdout, ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		denied := g.Status != nil &&
+			strings.HasSuffix(g.Status.Error(), "not permitted")
+		if denied == permitted {
+			t.Errorf("%q: %v", script, g.Status)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/internal/shellutils"
+)
+
+// FunctionsEnv has the Definition of each function of the shell that forks
+// a cli to run a subshell or background job, which defines them anew like
+// a pipeline stage.
+const FunctionsEnv = "GOES_FUNCTIONS"
+
+// stage returns the Goes of a pipeline stage other than the last. Such a
+// stage may run concurrently with the rest of the pipeline so, like a
+// subshell, it runs with a copy of the context taken as it starts, see
+// inherit, and its changes don't persist. Its forked commands are of the
+// job of the pipeline, that of the shell.
+func (g *Goes) stage() *Goes {
+	shell := g
+	if g.shell != nil {
+		shell = g.shell
+	}
+	return &Goes{
+		NAME:    g.NAME,
+		USAGE:   g.USAGE,
+		APROPOS: g.APROPOS,
+		MAN:     g.MAN,
+		ByName:  g.ByName,
+		Catline: g.Catline,
+		parent:  g.parent,
+		shell:   shell,
+	}
+}
+
+// inherit the context of the Goes running the pipeline of the stage.
+// Functions are compiled again for the stage when first called.
+func (g *Goes) inherit(from *Goes) {
+	g.Status = from.Status
+	g.Verbosity = from.Verbosity
+	g.ErrExit, g.PipeFail = from.ErrExit, from.PipeFail
+	g.ErrAbort = from.ErrAbort
+	g.JobControl = from.JobControl
+	g.inTest = from.inTest
+	g.Catline = from.Catline
+	g.ctx = from.Context()
+	from.jobs.Lock()
+	g.jobs.last = from.jobs.last
+	from.jobs.Unlock()
+	g.pipes = g.pipes[:0]
+	g.lastChild = nil
+	g.EnvMap = make(map[string]string, len(from.EnvMap))
+	for k, v := range from.EnvMap {
+		g.EnvMap[k] = v
+	}
+	g.AliasMap = make(map[string]string, len(from.AliasMap))
+	for k, v := range from.AliasMap {
+		g.AliasMap[k] = v
+	}
+	g.frames = make([]*frame, len(from.frames))
+	for i, f := range from.frames {
+		locals := make(map[string]saved, len(f.locals))
+		for k, v := range f.locals {
+			locals[k] = v
+		}
+		g.frames[i] = &frame{
+			args:   append([]string(nil), f.args...),
+			locals: locals,
+		}
+	}
+	g.FunctionMap = make(map[string]Function, len(from.FunctionMap))
+	for name, f := range from.FunctionMap {
+		if len(f.Definition) == 0 {
+			g.FunctionMap[name] = f
+			continue
+		}
+		g.FunctionMap[name] = g.lazy(f)
+	}
+}
+
+// lazy returns the function to be compiled from its Definition when first
+// called.
+func (g *Goes) lazy(f Function) Function {
+	return Function{
+		Name:       f.Name,
+		Definition: f.Definition,
+		RunFun: func(stdin io.Reader, stdout, stderr io.Writer) error {
+			cf, err := g.compile(f)
+			if err != nil {
+				return err
+			}
+			return cf.RunFun(stdin, stdout, stderr)
+		},
+	}
+}
+
+// functionsEnv returns the FunctionsEnv value of the functions that have a
+// Definition, if any.
+func (g *Goes) functionsEnv() (string, bool) {
+	defs := make(map[string][]string)
+	for name, f := range g.FunctionMap {
+		if len(f.Definition) > 0 {
+			defs[name] = f.Definition
+		}
+	}
+	if len(defs) == 0 {
+		return "", false
+	}
+	b, err := json.Marshal(defs)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// defineFunctions of the FunctionsEnv of the process, if any, to be
+// compiled when first called.
+func (g *Goes) defineFunctions() {
+	s, found := os.LookupEnv(FunctionsEnv)
+	if !found {
+		return
+	}
+	os.Unsetenv(FunctionsEnv)
+	var defs map[string][]string
+	if err := json.Unmarshal([]byte(s), &defs); err != nil {
+		return
+	}
+	if g.FunctionMap == nil {
+		g.FunctionMap = make(map[string]Function, len(defs))
+	}
+	for name, def := range defs {
+		g.FunctionMap[name] = g.lazy(Function{
+			Name:       name,
+			Definition: def,
+		})
+	}
+}
+
+// compile the Definition of the function, defining it anew in this Goes.
+func (g *Goes) compile(f Function) (Function, error) {
+	text := lines(f.Definition)
+	ls, err := shellutils.Parse("", &text)
+	if err != nil {
+		return f, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	name := ls.Cmds[0].Cmds[0].String()
+	method, found := g.ByName[name].(Blocker)
+	if !found {
+		return f, fmt.Errorf("%s: %s: not found", f.Name, name)
+	}
+	catline := g.Catline
+	g.Catline = &text
+	_, define, err := method.Block(g, *ls)
+	g.Catline = catline
+	if err != nil {
+		return f, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	if err = define(nil, nil, nil); err != nil {
+		return f, err
+	}
+	return g.FunctionMap[f.Name], nil
+}
+
+// isInProcess returns whether the command runs in-process when run, i.e.
+// is a function, builtin, or DontFork command, rather than forking.
+func (g *Goes) isInProcess(cl shellutils.Cmdline) func() bool {
+	return func() bool {
+		var name string
+		for _, w := range cl.Cmds {
+			if !isAssignment(w) {
+				name = w.String()
+				break
+			}
+		}
+		for expanded := make(map[string]bool); !expanded[name]; {
+			text, found := g.AliasMap[name]
+			if !found {
+				break
+			}
+			expanded[name] = true
+			acl, err := ParseAlias(text)
+			if err != nil || len(acl.Cmds) == 0 {
+				break
+			}
+			name = acl.Cmds[0].String()
+		}
+		if _, found := g.FunctionMap[name]; found {
+			return true
+		}
+		v, _ := g.lookup(name)
+		if v == nil {
+			_, found := g.Builtins()[name]
+			return found
+		}
+		k := cmd.WhatKind(v)
+		return !k.IsDaemon() &&
+			(k.IsDontFork() || g.inTest || name == os.Args[0])
+	}
+}
+
+// pipeline returns the job of the running pipeline, which is that of the
+// shell for the commands of a stage.
+func (g *Goes) pipeline() *jobs {
+	if g.shell != nil {
+		return &g.shell.jobs
+	}
+	return &g.jobs
+}
@@ -0,0 +1,75 @@
+// Copyright © 2015-2021 Platina Systems, Inc. All rights reserved.
+// Use of this source code is governed by the GPL-2 license described in the
+// LICENSE file.
+
+package goes_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/platinasystems/goes"
+	"github.com/platinasystems/goes/cmd"
+	"github.com/platinasystems/goes/cmd/cli"
+	"github.com/platinasystems/goes/cmd/echo"
+	forcmd "github.com/platinasystems/goes/cmd/for"
+	"github.com/platinasystems/goes/cmd/function"
+	"github.com/platinasystems/goes/cmd/grep"
+)
+
+// TestStage runs blocks and functions concurrently with the filter of their
+// output; run it with -race.
+func TestStage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script, fn := filepath.Join(dir, "script"), filepath.Join(dir, "out")
+	for _, tc := range []struct {
+		script, want string
+	}{
+		{
+			"for i in 1 2 3; do echo line $i; done | grep 2 > " + fn,
+			"line 2\n",
+		},
+		{
+			"function f { echo f $1; echo g $1; }\n" +
+				"f 1 | f 2 | grep f > " + fn,
+			"f 2\n",
+		},
+		{
+			"x=1\n" +
+				"for i in 2; do x=$i; echo $x; done | grep 2 > " +
+				fn + "\necho x=$x >> " + fn,
+			"2\nx=1\n",
+		},
+	} {
+		g := &goes.Goes{
+			NAME: "goes",
+			ByName: map[string]cmd.Cmd{
+				"cli":      &cli.Command{},
+				"echo":     echo.Command{},
+				"for":      forcmd.Command{},
+				"function": function.Command{},
+				"grep":     grep.Command{},
+			},
+		}
+		err := ioutil.WriteFile(script, []byte(tc.script), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Main("cli", script); err != nil {
+			t.Error(err)
+			continue
+		}
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Error(err)
+		} else if s := string(b); s != tc.want {
+			t.Errorf("%q: %q, want %q", tc.script, s, tc.want)
+		}
+	}
+}
@@ -17,6 +17,11 @@ type Usager interface {
 	Usage() string
 }
 
+// catalogUsage is the Usager of a command's text from a message catalog.
+type catalogUsage string
+
+func (s catalogUsage) Usage() string { return string(s) }
+
 func (g *Goes) Usage() string {
 	usage := g.USAGE
 	if len(usage) == 0 {
@@ -24,7 +29,7 @@ func (g *Goes) Usage() string {
 	goes COMMAND [ ARGS ]...
 	goes COMMAND -[-]HELPER [ ARGS ]...
 	goes HELPER [ COMMAND ] [ ARGS ]...
-	goes [ -d ] [ -x ] [[ -f ][ - | SCRIPT ]]
+	goes [ -d ] [ -x ] [[ -e | -f ][ - | SCRIPT ]]
 
 	HELPER := { apropos | complete | help | man | usage }`
 	}
@@ -32,13 +37,17 @@ func (g *Goes) Usage() string {
 }
 
 func (g *Goes) usage(args ...string) error {
-	var u Usager = g
+	var (
+		u    Usager = g
+		name string
+	)
 	if len(args) > 0 {
-		u = g.ByName[args[0]]
-		if u == nil {
+		v, found := g.lookup(args[0])
+		if !found {
 			return fmt.Errorf("%s: not found", args[0])
 		}
+		u, name = v, args[0]
 	}
-	fmt.Println(Usage(u))
+	fmt.Println(Usage(catalogUsage(g.usageOf(name, u))))
 	return nil
 }